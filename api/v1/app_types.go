@@ -33,6 +33,10 @@ import (
 //
 // +kubebuilder:validation:XValidation:rule="(self.provider == 'secret') == has(self.keyRef)",message="keyRef must be set if and only if provider is 'secret'"
 // +kubebuilder:validation:XValidation:rule="(self.provider != 'secret') == has(self.key)",message="key must be set if and only if provider is not 'secret'"
+// +kubebuilder:validation:XValidation:rule="has(self.installationID) != has(self.installation)",message="exactly one of installationID and installation must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.uploadURL) || has(self.baseURL)",message="uploadURL requires baseURL"
+// +kubebuilder:validation:XValidation:rule="!(has(self.caBundle) && has(self.caBundleRef))",message="caBundle and caBundleRef are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.caBundleRef) || !has(self.caBundleRef.__namespace__)",message="caBundleRef.namespace must not be set on an App"
 type AppSpec struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
@@ -42,12 +46,20 @@ type AppSpec struct {
 	// The AppID of the GitHub App.
 	AppID int64 `json:"appID"`
 
-	// +kubebuilder:validation:Required
+	// +optional
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:example:=123456789
 	// The default InstallationID of the GitHub App; Tokens/ClusterTokens may
-	// override this via spec.installationID to target a different installation.
-	InstallationID int64 `json:"installationID"`
+	// override this via spec.installationID or spec.installation to target a
+	// different installation. Exactly one of installationID and installation
+	// must be set.
+	InstallationID int64 `json:"installationID,omitempty"`
+
+	// +optional
+	// Selects the default installation by account login or repository
+	// instead of by ID. The App reconciler resolves it using the App's own
+	// credentials and records the result in status.installation.
+	Installation *InstallationSelector `json:"installation,omitempty"`
 
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Enum=secret;aws;azure;gcp;vault
@@ -76,6 +88,8 @@ type AppSpec struct {
 	// If true, the operator validates the private key at reconcile time by
 	// attempting a test sign. Failures surface as a KeyValid=False condition.
 	ValidateKey bool `json:"validateKey,omitempty"`
+
+	GitHubEndpoint `json:",inline"`
 }
 
 // KeySecretReference identifies a same-namespace Secret holding a
@@ -101,6 +115,21 @@ type AppStatus struct {
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
+	// +optional
+	// Installation records the ID that spec.installation resolved to, so
+	// that it is not looked up again on every reconcile.
+	Installation *ResolvedInstallation `json:"installation,omitempty"`
+
+	// +optional
+	// Installations of the GitHub App, refreshed periodically.
+	Installations []InstallationStatus `json:"installations,omitempty"`
+
+	// +optional
+	// InstallationCount is the number of entries in installations.
+	InstallationCount int32 `json:"installationCount,omitempty"`
+
+	FailureStatus `json:",inline"`
+
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
 }
@@ -111,6 +140,7 @@ type AppStatus struct {
 // +kubebuilder:printcolumn:name="App ID",type=integer,JSONPath=`.spec.appID`
 // +kubebuilder:printcolumn:name="Installation ID",type=integer,JSONPath=`.spec.installationID`
 // +kubebuilder:printcolumn:name="Provider",type=string,JSONPath=`.spec.provider`
+// +kubebuilder:printcolumn:name="Installations",type=integer,JSONPath=`.status.installationCount`
 // +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
@@ -124,6 +154,79 @@ type App struct {
 	Status AppStatus `json:"status,omitempty"`
 }
 
+func (a *App) GetType() string {
+	return "App"
+}
+
+func (a *App) GetAppID() int64 {
+	return a.Spec.AppID
+}
+
+func (a *App) GetInstallationID() int64 {
+	return a.Spec.InstallationID
+}
+
+func (a *App) GetInstallationSelector() *InstallationSelector {
+	return a.Spec.Installation
+}
+
+func (a *App) GetProvider() string {
+	return a.Spec.Provider
+}
+
+func (a *App) GetKey() string {
+	return a.Spec.Key
+}
+
+func (a *App) GetValidateKey() bool {
+	return a.Spec.ValidateKey
+}
+
+func (a *App) GetBaseURL() string {
+	return a.Spec.BaseURL
+}
+
+func (a *App) GetUploadURL() string {
+	return a.Spec.UploadURL
+}
+
+func (a *App) GetProxyURL() string {
+	return a.Spec.ProxyURL
+}
+
+func (a *App) GetCABundle() string {
+	return a.Spec.CABundle
+}
+
+// GetKeySecretRef returns the Secret holding the private key, qualified with
+// the App's own namespace, or nil unless the provider is "secret".
+func (a *App) GetKeySecretRef() *ClusterKeySecretReference {
+	if a.Spec.KeyRef == nil {
+		return nil
+	}
+	return &ClusterKeySecretReference{
+		Namespace: a.Namespace,
+		Name:      a.Spec.KeyRef.Name,
+		Key:       a.Spec.KeyRef.Key,
+	}
+}
+
+// GetCABundleRef returns the ConfigMap or Secret holding the CA bundle,
+// qualified with the App's own namespace, or nil if none is referenced.
+func (a *App) GetCABundleRef() *CABundleReference {
+	if a.Spec.CABundleRef == nil {
+		return nil
+	}
+	ref := *a.Spec.CABundleRef
+	ref.Namespace = a.Namespace
+	return &ref
+}
+
+// GetAppStatus returns a pointer to the App's status.
+func (a *App) GetAppStatus() *AppStatus {
+	return &a.Status
+}
+
 // GetStatusConditions returns the App's status conditions slice.
 func (a *App) GetStatusConditions() []metav1.Condition {
 	return a.Status.Conditions
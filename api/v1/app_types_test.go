@@ -74,6 +74,21 @@ func TestToken_GetAppRef(t *testing.T) {
 			t.Errorf("Namespace = %v, want team-a (Token's namespace)", got.Namespace)
 		}
 	})
+	t.Run("explicit namespace is honored", func(t *testing.T) {
+		tok := &v1.Token{
+			ObjectMeta: metav1.ObjectMeta{Name: "t", Namespace: "team-a"},
+			Spec: v1.TokenSpec{
+				AppRef: &v1.LocalAppReference{Name: "shared-app", Namespace: "platform"},
+			},
+		}
+		got := tok.GetAppRef()
+		if got == nil {
+			t.Fatalf("GetAppRef() = nil, want non-nil")
+		}
+		if got.Namespace != "platform" {
+			t.Errorf("Namespace = %v, want platform", got.Namespace)
+		}
+	})
 }
 
 func TestClusterToken_GetAppRef(t *testing.T) {
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// AppGrantSpec defines which Apps in the grant's namespace may be referenced,
+// and from which namespaces.
+//
+// +kubebuilder:validation:XValidation:rule="has(self.namespaces) || has(self.namespaceSelector)",message="at least one of namespaces and namespaceSelector must be set"
+type AppGrantSpec struct {
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:MaxItems:=64
+	// +kubebuilder:example:={"shared-app"}
+	// Names of the Apps in this namespace covered by the grant (defaults to
+	// every App in the namespace)
+	Apps []string `json:"apps,omitempty"`
+
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:MaxItems:=256
+	// +kubebuilder:example:={"team-a", "team-b"}
+	// Namespaces whose Tokens may reference the covered Apps
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// +optional
+	// Namespaces, selected by label, whose Tokens may reference the covered
+	// Apps
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced,shortName=ag,path=appgrants
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AppGrant lets Tokens in other namespaces reference Apps in the grant's own
+// namespace. It is created by the App owner, in the manner of the Gateway
+// API's ReferenceGrant.
+type AppGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AppGrantSpec `json:"spec,omitempty"`
+}
+
+// Grants reports whether the grant lets Tokens in namespace reference the App
+// named app in the grant's namespace.
+func (g *AppGrant) Grants(app string, namespace *corev1.Namespace) (bool, error) {
+	if len(g.Spec.Apps) > 0 && !slices.Contains(g.Spec.Apps, app) {
+		return false, nil
+	}
+	if slices.Contains(g.Spec.Namespaces, namespace.Name) {
+		return true, nil
+	}
+	if g.Spec.NamespaceSelector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(g.Spec.NamespaceSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(namespace.Labels)), nil
+}
+
+// +kubebuilder:object:root=true
+
+// AppGrantList contains a list of AppGrant.
+type AppGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []AppGrant `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AppGrant{}, &AppGrantList{})
+}
@@ -0,0 +1,77 @@
+package v1_test
+
+import (
+	"testing"
+
+	v1 "github.com/isometry/github-token-manager/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAppGrant_Grants(t *testing.T) {
+	teamA := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
+	tenant := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"tenant": "true"}}}
+	other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+
+	tests := []struct {
+		name      string
+		spec      v1.AppGrantSpec
+		app       string
+		namespace *corev1.Namespace
+		want      bool
+	}{
+		{
+			name:      "listed namespace, any app",
+			spec:      v1.AppGrantSpec{Namespaces: []string{"team-a"}},
+			app:       "shared-app",
+			namespace: teamA,
+			want:      true,
+		},
+		{
+			name:      "unlisted namespace",
+			spec:      v1.AppGrantSpec{Namespaces: []string{"team-a"}},
+			app:       "shared-app",
+			namespace: other,
+		},
+		{
+			name:      "app not covered",
+			spec:      v1.AppGrantSpec{Apps: []string{"other-app"}, Namespaces: []string{"team-a"}},
+			app:       "shared-app",
+			namespace: teamA,
+		},
+		{
+			name: "selected namespace",
+			spec: v1.AppGrantSpec{
+				Apps:              []string{"shared-app"},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+			},
+			app:       "shared-app",
+			namespace: tenant,
+			want:      true,
+		},
+		{
+			name: "unselected namespace",
+			spec: v1.AppGrantSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+			},
+			app:       "shared-app",
+			namespace: other,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grant := &v1.AppGrant{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "platform", Name: "grant"},
+				Spec:       tt.spec,
+			}
+			got, err := grant.Grants(tt.app, tt.namespace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Grants() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,28 +16,56 @@ limitations under the License.
 
 package v1
 
-// LocalAppReference is a same-namespace reference to an App resource used
-// by the namespaced Token kind. A Token may only reference an App in its
-// own namespace.
+// LocalAppReference is a reference to an App resource used by the namespaced
+// Token kind. The App is in the Token's own namespace unless Namespace is
+// set, in which case an AppGrant in that namespace must permit the reference.
 type LocalAppReference struct {
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MaxLength:=253
-	// Name of the App resource in the same namespace as the referring Token.
+	// Name of the App resource.
 	Name string `json:"name"`
+
+	// +optional
+	// +kubebuilder:validation:MaxLength:=253
+	// Namespace containing the App resource (defaults to the Token's own
+	// namespace). A different namespace is only honored when an AppGrant
+	// there permits Tokens from this namespace to reference the App.
+	Namespace string `json:"namespace,omitempty"`
 }
 
+const (
+	// AppKindApp is the kind of a namespaced App.
+	AppKindApp = "App"
+	// AppKindClusterApp is the kind of a cluster-scoped ClusterApp.
+	AppKindClusterApp = "ClusterApp"
+)
+
 // AppReference identifies an App resource, optionally in a different
-// namespace. Used by the cluster-scoped ClusterToken kind. When Namespace is
-// empty the controller resolves it to the operator's own namespace.
+// namespace, or a ClusterApp. Used by the cluster-scoped ClusterToken kind.
+// When Namespace is empty for an App the controller resolves it to the
+// operator's own namespace.
+//
+// +kubebuilder:validation:XValidation:rule="!has(self.kind) || self.kind != 'ClusterApp' || !has(self.__namespace__)",message="namespace must not be set when kind is ClusterApp"
 type AppReference struct {
+	// +optional
+	// +kubebuilder:validation:Enum=App;ClusterApp
+	// +kubebuilder:default:=App
+	// Kind of the referenced resource: App (default) or ClusterApp.
+	Kind string `json:"kind,omitempty"`
+
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MaxLength:=253
-	// Name of the App resource.
+	// Name of the App or ClusterApp resource.
 	Name string `json:"name"`
 
 	// +optional
 	// +kubebuilder:validation:MaxLength:=253
 	// Namespace containing the App resource. If empty, defaults to the
-	// operator's own namespace.
+	// operator's own namespace. Must be unset when kind is ClusterApp.
 	Namespace string `json:"namespace,omitempty"`
 }
+
+// IsClusterApp reports whether the reference is to a ClusterApp.
+func (r *AppReference) IsClusterApp() bool {
+	return r.Kind == AppKindClusterApp
+}
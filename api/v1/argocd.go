@@ -0,0 +1,63 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// ArgoCDSecretTypeLabel is the label Argo CD uses to discover repository
+// Secrets.
+const ArgoCDSecretTypeLabel = "argocd.argoproj.io/secret-type"
+
+// DefaultArgoCDURL is the URL prefix written when spec.secret.argoCD.url is
+// unset.
+const DefaultArgoCDURL = "https://github.com/"
+
+// ArgoCDSpec configures an Argo CD repository credential Secret.
+type ArgoCDSpec struct {
+	// +optional
+	// +kubebuilder:validation:Enum=repository;repo-creds
+	// +kubebuilder:default:="repo-creds"
+	// Argo CD Secret type: 'repository' for a single repository, or
+	// 'repo-creds' for a credential template matched by URL prefix
+	SecretType string `json:"secretType,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:MaxLength:=2048
+	// +kubebuilder:example:="https://github.com/my-org/"
+	// Repository URL ('repository') or URL prefix ('repo-creds') written to
+	// the 'url' key (defaults to https://github.com/)
+	URL string `json:"url,omitempty"`
+
+	// +optional
+	// Also write the GitHub App ID to the 'githubAppID' key
+	IncludeGitHubAppID bool `json:"includeGitHubAppID,omitempty"`
+}
+
+// GetSecretType returns the Argo CD Secret type, defaulting to 'repo-creds'.
+func (a *ArgoCDSpec) GetSecretType() string {
+	if a.SecretType == "" {
+		return "repo-creds"
+	}
+	return a.SecretType
+}
+
+// GetURL returns the repository URL or prefix, defaulting to
+// [DefaultArgoCDURL].
+func (a *ArgoCDSpec) GetURL() string {
+	if a.URL == "" {
+		return DefaultArgoCDURL
+	}
+	return a.URL
+}
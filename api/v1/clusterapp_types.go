@@ -0,0 +1,206 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterAppSpec defines the desired state of a ClusterApp. It mirrors
+// AppSpec, except that a "secret" provider names the Secret's namespace
+// explicitly.
+//
+// +kubebuilder:validation:XValidation:rule="(self.provider == 'secret') == has(self.keyRef)",message="keyRef must be set if and only if provider is 'secret'"
+// +kubebuilder:validation:XValidation:rule="(self.provider != 'secret') == has(self.key)",message="key must be set if and only if provider is not 'secret'"
+// +kubebuilder:validation:XValidation:rule="has(self.installationID) != has(self.installation)",message="exactly one of installationID and installation must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.uploadURL) || has(self.baseURL)",message="uploadURL requires baseURL"
+// +kubebuilder:validation:XValidation:rule="!(has(self.caBundle) && has(self.caBundleRef))",message="caBundle and caBundleRef are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.caBundleRef) || has(self.caBundleRef.__namespace__)",message="caBundleRef.namespace is required on a ClusterApp"
+type ClusterAppSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:example:=12345
+	// The AppID of the GitHub App.
+	AppID int64 `json:"appID"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:example:=123456789
+	// The default InstallationID of the GitHub App; ClusterTokens may
+	// override this via spec.installationID or spec.installation to target a
+	// different installation. Exactly one of installationID and installation
+	// must be set.
+	InstallationID int64 `json:"installationID,omitempty"`
+
+	// +optional
+	// Selects the default installation by account login or repository
+	// instead of by ID. The App reconciler resolves it using the App's own
+	// credentials and records the result in status.installation.
+	Installation *InstallationSelector `json:"installation,omitempty"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=secret;aws;azure;gcp;vault
+	// Private key provider. One of "secret" (PEM material in a Secret,
+	// referenced by keyRef), "aws" (AWS KMS), "azure" (Azure Key Vault),
+	// "gcp" (Google Cloud KMS), or "vault" (HashiCorp Vault transit).
+	Provider string `json:"provider"`
+
+	// +optional
+	// Cloud-KMS key reference. Required when provider is "aws", "azure",
+	// "gcp", or "vault"; forbidden when provider is "secret".
+	Key string `json:"key,omitempty"`
+
+	// +optional
+	// Secret reference holding the PEM-encoded RSA private key. Required
+	// when provider is "secret"; forbidden otherwise.
+	KeyRef *ClusterKeySecretReference `json:"keyRef,omitempty"`
+
+	// +optional
+	// +kubebuilder:default:=false
+	// If true, the operator validates the private key at reconcile time by
+	// attempting a test sign. Failures surface as a KeyValid=False condition.
+	ValidateKey bool `json:"validateKey,omitempty"`
+
+	GitHubEndpoint `json:",inline"`
+}
+
+// ClusterKeySecretReference identifies a Secret, in an explicit namespace,
+// holding a PEM-encoded RSA private key for a GitHub App.
+type ClusterKeySecretReference struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength:=253
+	// Namespace of the Secret.
+	Namespace string `json:"namespace"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength:=253
+	// Name of the Secret.
+	Name string `json:"name"`
+
+	// +optional
+	// +kubebuilder:default:="private-key.pem"
+	// Key within the Secret's data map containing the PEM-encoded RSA
+	// private key. Defaults to "private-key.pem".
+	Key string `json:"key,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=capp,path=clusterapps
+// +kubebuilder:printcolumn:name="App ID",type=integer,JSONPath=`.spec.appID`
+// +kubebuilder:printcolumn:name="Installation ID",type=integer,JSONPath=`.spec.installationID`
+// +kubebuilder:printcolumn:name="Provider",type=string,JSONPath=`.spec.provider`
+// +kubebuilder:printcolumn:name="Installations",type=integer,JSONPath=`.status.installationCount`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterApp is the Schema for the clusterapps API; it encapsulates a GitHub
+// App configuration that ClusterTokens may reference via spec.appRef with
+// kind ClusterApp, independent of any namespace.
+type ClusterApp struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterAppSpec `json:"spec,omitempty"`
+	Status AppStatus      `json:"status,omitempty"`
+}
+
+func (a *ClusterApp) GetType() string {
+	return "ClusterApp"
+}
+
+func (a *ClusterApp) GetAppID() int64 {
+	return a.Spec.AppID
+}
+
+func (a *ClusterApp) GetInstallationID() int64 {
+	return a.Spec.InstallationID
+}
+
+func (a *ClusterApp) GetInstallationSelector() *InstallationSelector {
+	return a.Spec.Installation
+}
+
+func (a *ClusterApp) GetProvider() string {
+	return a.Spec.Provider
+}
+
+func (a *ClusterApp) GetKey() string {
+	return a.Spec.Key
+}
+
+func (a *ClusterApp) GetValidateKey() bool {
+	return a.Spec.ValidateKey
+}
+
+func (a *ClusterApp) GetBaseURL() string {
+	return a.Spec.BaseURL
+}
+
+func (a *ClusterApp) GetUploadURL() string {
+	return a.Spec.UploadURL
+}
+
+func (a *ClusterApp) GetProxyURL() string {
+	return a.Spec.ProxyURL
+}
+
+func (a *ClusterApp) GetCABundle() string {
+	return a.Spec.CABundle
+}
+
+// GetKeySecretRef returns the Secret holding the private key, or nil unless
+// the provider is "secret".
+func (a *ClusterApp) GetKeySecretRef() *ClusterKeySecretReference {
+	return a.Spec.KeyRef
+}
+
+// GetCABundleRef returns the ConfigMap or Secret holding the CA bundle, or
+// nil if none is referenced.
+func (a *ClusterApp) GetCABundleRef() *CABundleReference {
+	return a.Spec.CABundleRef
+}
+
+// GetAppStatus returns a pointer to the ClusterApp's status.
+func (a *ClusterApp) GetAppStatus() *AppStatus {
+	return &a.Status
+}
+
+// GetStatusConditions returns the ClusterApp's status conditions slice.
+func (a *ClusterApp) GetStatusConditions() []metav1.Condition {
+	return a.Status.Conditions
+}
+
+// SetStatusCondition updates the ClusterApp's status conditions in place,
+// returning true when the resulting slice differs from the prior value.
+func (a *ClusterApp) SetStatusCondition(condition metav1.Condition) (changed bool) {
+	return meta.SetStatusCondition(&a.Status.Conditions, condition)
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterAppList contains a list of ClusterApp.
+type ClusterAppList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ClusterApp `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterApp{}, &ClusterAppList{})
+}
@@ -17,21 +17,28 @@ limitations under the License.
 package v1
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/go-github/v84/github"
 	"github.com/isometry/github-token-manager/internal/ghapp"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // ClusterTokenSpec defines the desired state of ClusterToken
+//
+// +kubebuilder:validation:XValidation:rule="!(has(self.installationID) && has(self.installation))",message="installationID and installation are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.repositorySelector) || !(has(self.repositories) || has(self.repositoryIDs))",message="repositorySelector is mutually exclusive with repositories and repositoryIDs"
+// +kubebuilder:validation:XValidation:rule="!(has(self.shareToken) && self.shareToken && has(self.revoke) && self.revoke)",message="shareToken and revoke are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!(has(self.revoke) && self.revoke && has(self.secret) && has(self.secret.template))",message="revoke and secret.template are mutually exclusive"
 type ClusterTokenSpec struct {
 	// +optional
-	// Reference to the App that provides the GitHub App credentials for this
-	// ClusterToken. When spec.appRef.namespace is empty, the operator resolves
-	// the reference in its own namespace. When unset, the operator's startup
-	// configuration is used.
+	// Reference to the App or ClusterApp that provides the GitHub App
+	// credentials for this ClusterToken. When spec.appRef.namespace is empty,
+	// the operator resolves an App reference in its own namespace. When
+	// unset, the operator's startup configuration is used.
 	AppRef *AppReference `json:"appRef,omitempty"`
 
 	// +kubebuilder:validation:Required
@@ -42,6 +49,12 @@ type ClusterTokenSpec struct {
 	// Specify or override the InstallationID of the GitHub App for this Token
 	InstallationID int64 `json:"installationID,omitempty"`
 
+	// +optional
+	// Override the installation of the GitHub App for this Token by account
+	// login or repository instead of by ID. Mutually exclusive with
+	// installationID.
+	Installation *InstallationSelector `json:"installation,omitempty"`
+
 	// +optional
 	// +kubebuilder:validation:Format:=duration
 	// +kubebuilder:default:="30m"
@@ -49,13 +62,42 @@ type ClusterTokenSpec struct {
 	// Specify how often to refresh the token (maximum: 1h)
 	RefreshInterval metav1.Duration `json:"refreshInterval"`
 
+	// +optional
+	// +kubebuilder:validation:Format:=duration
+	// +kubebuilder:example:="10m"
+	// Refresh the token this long before it expires, instead of once
+	// refreshInterval has elapsed since it was minted
+	RefreshWindow *metav1.Duration `json:"refreshWindow,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Format:=duration
+	// +kubebuilder:example:="2m"
+	// Bring each refresh forward by up to this long, spreading the refreshes
+	// of tokens minted together (defaults to a tenth of the time between
+	// minting and refreshing the token)
+	RefreshJitter *metav1.Duration `json:"refreshJitter,omitempty"`
+
 	// +optional
 	// +kubebuilder:validation:Format:=duration
 	// +kubebuilder:default:="5m"
 	// +kubebuilder:example:="1m"
-	// Specify how long to wait before retrying on transient token retrieval error
+	// Longest to wait before retrying after a failed reconcile: retries back
+	// off exponentially with each failure in a row, up to this interval or
+	// the operator's --retry-max-delay, whichever is shorter
 	RetryInterval metav1.Duration `json:"retryInterval"`
 
+	// +optional
+	// Revoke the outgoing installation token once its replacement has been
+	// written, and revoke the live token when this ClusterToken is deleted
+	Revoke bool `json:"revoke,omitempty"`
+
+	// +optional
+	// Share the installation token with every other Token or ClusterToken of
+	// the same App and installation that also sets shareToken and requests
+	// identical permissions and repositories, minting it once for all of
+	// them. Each still gets its own Secret. Mutually exclusive with revoke
+	ShareToken bool `json:"shareToken,omitempty"`
+
 	// +optional
 	// +kubebuilder:example:={"metadata": "read", "contents": "read"}
 	// Specify the permissions for the token as a subset of those of the GitHub App
@@ -70,14 +112,29 @@ type ClusterTokenSpec struct {
 	// +kubebuilder:validation:MaxItems:=500
 	// Specify the repository IDs for which the token should have access
 	RepositoryIDs []int64 `json:"repositoryIDs,omitempty"`
+
+	// +optional
+	// Select the repositories for which the token should have access from
+	// those accessible to the installation, by name, topic or custom
+	// property. Mutually exclusive with repositories and repositoryIDs.
+	RepositorySelector *RepositorySelector `json:"repositorySelector,omitempty"`
 }
 
+// +kubebuilder:validation:XValidation:rule="[has(self.basicAuth) && self.basicAuth, has(self.template), has(self.dockerConfigJSON), has(self.argoCD)].filter(x, x).size() <= 1",message="at most one of basicAuth, template, dockerConfigJSON and argoCD may be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.template) || !has(self.gitFormats) || !self.gitFormats.exists(f, ('.' + f) in self.template)",message="template keys must not collide with the data keys of gitFormats"
+// +kubebuilder:validation:XValidation:rule="has(self.__namespace__) != has(self.namespaceSelector)",message="exactly one of namespace and namespaceSelector must be set"
 type ClusterTokenSecretSpec struct {
-	// +kubebuilder:validation:Required
+	// +optional
 	// +kubebuilder:validation:MaxLength:=253
 	// +kubebuilder:example:="default"
 	// Namespace for the Secret managed by this ClusterToken
-	Namespace string `json:"namespace"`
+	Namespace string `json:"namespace,omitempty"`
+
+	// +optional
+	// +kubebuilder:example:={"matchLabels": {"tenant": "true"}}
+	// Write a copy of the Secret managed by this ClusterToken into every
+	// namespace matching this label selector, instead of a single namespace
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
 
 	// +optional
 	// +kubebuilder:validation:MaxLength:=253
@@ -85,7 +142,8 @@ type ClusterTokenSecretSpec struct {
 	Name string `json:"name,omitempty"`
 
 	// +optional
-	// Extra labels for the Secret managed by this Token
+	// Extra labels for the Secret managed by this Token. The
+	// app.kubernetes.io labels identifying the Token cannot be overridden.
 	Labels map[string]string `json:"labels,omitempty"`
 
 	// +optional
@@ -95,20 +153,76 @@ type ClusterTokenSecretSpec struct {
 	// +optional
 	// Create a secret with 'username' and 'password' fields for HTTP Basic Auth rather than simply 'token'
 	BasicAuth bool `json:"basicAuth,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:MaxProperties:=64
+	// +kubebuilder:validation:XValidation:rule="self.all(k, k.matches('^[-._a-zA-Z0-9]+$'))",message="template keys must be valid Secret data keys"
+	// +kubebuilder:example:={".npmrc": "//npm.pkg.github.com/:_authToken={{ .Token }}"}
+	// Render the Secret data from Go text/template strings keyed by data key,
+	// rather than simply 'token'. Templates may reference .Token, .ExpiresAt,
+	// .AppID, .InstallationID and .Repositories. Their syntax is only checked
+	// on admission when the operator's webhook is enabled.
+	Template map[string]string `json:"template,omitempty"`
+
+	// +optional
+	// Create a 'kubernetes.io/dockerconfigjson' Secret for image pulls from
+	// ghcr.io (and any extra registries) rather than simply 'token'
+	DockerConfigJSON *DockerConfigJSONSpec `json:"dockerConfigJSON,omitempty"`
+
+	// +optional
+	// Create an Argo CD repository credential Secret with 'url', 'username'
+	// and 'password' keys and the argocd.argoproj.io/secret-type label
+	// rather than simply 'token'
+	ArgoCD *ArgoCDSpec `json:"argoCD,omitempty"`
+
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:MaxItems:=3
+	// +kubebuilder:example:={"netrc", "gitconfig"}
+	// Additionally write git credential files for the token, each under its
+	// own data key: 'netrc' (.netrc), 'git-credentials' (.git-credentials)
+	// and/or 'gitconfig' (.gitconfig with url.insteadOf rewrites)
+	GitFormats []GitFormat `json:"gitFormats,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:MaxLength:=253
+	// +kubebuilder:validation:Pattern=`^([a-zA-Z0-9]([-a-zA-Z0-9]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([-a-zA-Z0-9]{0,61}[a-zA-Z0-9])?(:[0-9]{1,5})?$`
+	// +kubebuilder:example:="github.example.com"
+	// Host for which git credential files are written, with an optional
+	// port (defaults to github.com)
+	GitHost string `json:"gitHost,omitempty"`
 }
 
 // ClusterTokenStatus defines the observed state of ClusterToken
 type ClusterTokenStatus struct {
 	ManagedSecret ManagedSecret `json:"managedSecret,omitempty"`
 
+	// +optional
+	// Namespaces currently holding a copy of the Secret when
+	// spec.secret.namespaceSelector is set
+	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+
 	IAT InstallationAccessToken `json:"installationAccessToken,omitempty"`
 
+	TokenGrant `json:",inline"`
+
+	// +optional
+	// Names of the repositories spec.repositorySelector resolved to when
+	// the token held in the Secret was minted
+	SelectedRepositories []string `json:"selectedRepositories,omitempty"`
+
+	FailureStatus `json:",inline"`
+
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.installationAccessToken.expiresAt`
+// +kubebuilder:printcolumn:name="Repositories",type=integer,JSONPath=`.status.repositoryCount`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // ClusterToken is the Schema for the clustertokens API
 type ClusterToken struct {
@@ -127,14 +241,19 @@ func (t *ClusterToken) GetInstallationID() int64 {
 	return t.Spec.InstallationID
 }
 
+func (t *ClusterToken) GetInstallationSelector() *InstallationSelector {
+	return t.Spec.Installation
+}
+
 // GetAppRef returns the raw *AppReference set on the ClusterToken, or nil if
-// unset. The Namespace field may be empty; the caller (registry) defaults it
-// to the operator's own namespace.
+// unset. The Namespace field may be empty; for an App the caller (registry)
+// defaults it to the operator's own namespace.
 func (t *ClusterToken) GetAppRef() *AppReference {
 	if t.Spec.AppRef == nil {
 		return nil
 	}
 	return &AppReference{
+		Kind:      t.Spec.AppRef.Kind,
 		Name:      t.Spec.AppRef.Name,
 		Namespace: t.Spec.AppRef.Namespace,
 	}
@@ -144,14 +263,38 @@ func (t *ClusterToken) GetRefreshInterval() time.Duration {
 	return t.Spec.RefreshInterval.Duration
 }
 
+// GetRefreshWindow returns how long before expiry the token is refreshed.
+func (t *ClusterToken) GetRefreshWindow() time.Duration {
+	return refreshWindow(t.Spec.RefreshWindow, t.Spec.RefreshInterval.Duration)
+}
+
+// GetRefreshJitter returns the most each refresh is brought forward by.
+func (t *ClusterToken) GetRefreshJitter() time.Duration {
+	return refreshJitter(t.Spec.RefreshJitter, t.GetRefreshWindow())
+}
+
+// GetRetryInterval returns the longest delay between retries after failed
+// reconciles.
 func (t *ClusterToken) GetRetryInterval() time.Duration {
 	return t.Spec.RetryInterval.Duration
 }
 
+func (t *ClusterToken) GetRevoke() bool {
+	return t.Spec.Revoke
+}
+
+func (t *ClusterToken) GetShareToken() bool {
+	return t.Spec.ShareToken
+}
+
 func (t *ClusterToken) GetSecretNamespace() string {
 	return t.Spec.Secret.Namespace
 }
 
+func (t *ClusterToken) GetSecretNamespaceSelector() *metav1.LabelSelector {
+	return t.Spec.Secret.NamespaceSelector
+}
+
 // GetSecretName returns the name of the Secret for the Token
 func (t *ClusterToken) GetSecretName() string {
 	secretName := t.Name
@@ -173,6 +316,32 @@ func (t *ClusterToken) GetSecretBasicAuth() bool {
 	return t.Spec.Secret.BasicAuth
 }
 
+func (t *ClusterToken) GetSecretTemplate() map[string]string {
+	return t.Spec.Secret.Template
+}
+
+func (t *ClusterToken) GetSecretDockerConfigJSON() *DockerConfigJSONSpec {
+	return t.Spec.Secret.DockerConfigJSON
+}
+
+func (t *ClusterToken) GetSecretArgoCD() *ArgoCDSpec {
+	return t.Spec.Secret.ArgoCD
+}
+
+func (t *ClusterToken) GetSecretGitFormats() []GitFormat {
+	return t.Spec.Secret.GitFormats
+}
+
+// GetSecretGitHost returns the host for git credential files in the Secret
+func (t *ClusterToken) GetSecretGitHost() string {
+	return gitHost(t.Spec.Secret.GitHost)
+}
+
+// GetSecretType returns the type of the Secret managed by the ClusterToken
+func (t *ClusterToken) GetSecretType() corev1.SecretType {
+	return secretType(t.Spec.Secret.BasicAuth, t.Spec.Secret.Template, t.Spec.Secret.DockerConfigJSON, t.Spec.Secret.ArgoCD)
+}
+
 func (t *ClusterToken) GetInstallationTokenOptions() *github.InstallationTokenOptions {
 	return &github.InstallationTokenOptions{
 		Permissions:   t.Spec.Permissions.ToInstallationPermissions(),
@@ -181,6 +350,12 @@ func (t *ClusterToken) GetInstallationTokenOptions() *github.InstallationTokenOp
 	}
 }
 
+// GetRepositorySelector returns the selector from which the repositories
+// the token is limited to are resolved, or nil if they are listed.
+func (t *ClusterToken) GetRepositorySelector() *RepositorySelector {
+	return t.Spec.RepositorySelector
+}
+
 func (t *ClusterToken) GetManagedSecret() ManagedSecret {
 	return t.Status.ManagedSecret
 }
@@ -191,6 +366,10 @@ func (t *ClusterToken) UpdateManagedSecret() (changed bool) {
 			Namespace: t.GetSecretNamespace(),
 			Name:      t.GetSecretName(),
 			BasicAuth: t.GetSecretBasicAuth(),
+			Type:      t.GetSecretType(),
+		}
+		if t.Spec.Secret.NamespaceSelector == nil {
+			t.Status.TargetNamespaces = nil
 		}
 		return true
 	}
@@ -206,6 +385,34 @@ func (t *ClusterToken) SetStatusTimestamps(expiresAt time.Time) {
 	t.Status.IAT.CreatedAt = metav1.NewTime(t.Status.IAT.ExpiresAt.Add(-ghapp.TokenValidity))
 }
 
+func (t *ClusterToken) GetStatusTargetNamespaces() []string {
+	return t.Status.TargetNamespaces
+}
+
+func (t *ClusterToken) SetStatusTargetNamespaces(namespaces []string) (changed bool) {
+	if slices.Equal(t.Status.TargetNamespaces, namespaces) {
+		return false
+	}
+	t.Status.TargetNamespaces = namespaces
+	return true
+}
+
+// SetStatusGrant records the access granted to the installation token held
+// in the Secret.
+func (t *ClusterToken) SetStatusGrant(token *github.InstallationToken) {
+	t.Status.TokenGrant = NewTokenGrant(token)
+}
+
+// SetStatusSelectedRepositories records the repositories the repository
+// selector resolved to for the installation token held in the Secret.
+func (t *ClusterToken) SetStatusSelectedRepositories(names []string) (changed bool) {
+	if slices.Equal(t.Status.SelectedRepositories, names) {
+		return false
+	}
+	t.Status.SelectedRepositories = names
+	return true
+}
+
 func (t *ClusterToken) GetStatusConditions() []metav1.Condition {
 	return t.Status.Conditions
 }
@@ -214,6 +421,11 @@ func (t *ClusterToken) SetStatusCondition(condition metav1.Condition) (changed b
 	return meta.SetStatusCondition(&t.Status.Conditions, condition)
 }
 
+// GetStatusFailures returns the failed reconciles recorded in status.
+func (t *ClusterToken) GetStatusFailures() *FailureStatus {
+	return &t.Status.FailureStatus
+}
+
 // +kubebuilder:object:root=true
 
 // ClusterTokenList contains a list of ClusterToken
@@ -227,6 +227,32 @@ func TestClusterToken_UpdateManagedSecret(t *testing.T) {
 			wantName:      "test-cluster-token",
 			wantBasicAuth: false,
 		},
+		{
+			name: "namespace selector records no namespace",
+			token: &v1.ClusterToken{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-cluster-token",
+				},
+				Spec: v1.ClusterTokenSpec{
+					Secret: v1.ClusterTokenSecretSpec{
+						NamespaceSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"tenant": "true"},
+						},
+					},
+				},
+				Status: v1.ClusterTokenStatus{
+					ManagedSecret: v1.ManagedSecret{
+						Namespace: "production",
+						Name:      "test-cluster-token",
+						BasicAuth: false,
+					},
+				},
+			},
+			wantChanged:   true,
+			wantNamespace: "",
+			wantName:      "test-cluster-token",
+			wantBasicAuth: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -240,6 +266,49 @@ func TestClusterToken_UpdateManagedSecret(t *testing.T) {
 	}
 }
 
+func TestClusterToken_SetStatusTargetNamespaces(t *testing.T) {
+	token := &v1.ClusterToken{}
+
+	if !token.SetStatusTargetNamespaces([]string{"team-a", "team-b"}) {
+		t.Error("SetStatusTargetNamespaces() changed = false on first set, want true")
+	}
+	if token.SetStatusTargetNamespaces([]string{"team-a", "team-b"}) {
+		t.Error("SetStatusTargetNamespaces() changed = true for identical namespaces, want false")
+	}
+	if !token.SetStatusTargetNamespaces([]string{"team-a"}) {
+		t.Error("SetStatusTargetNamespaces() changed = false after removal, want true")
+	}
+	if got := token.GetStatusTargetNamespaces(); len(got) != 1 || got[0] != "team-a" {
+		t.Errorf("GetStatusTargetNamespaces() = %v, want [team-a]", got)
+	}
+}
+
+func TestClusterToken_UpdateManagedSecret_ClearsTargetNamespaces(t *testing.T) {
+	token := &v1.ClusterToken{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-cluster-token",
+		},
+		Spec: v1.ClusterTokenSpec{
+			Secret: v1.ClusterTokenSecretSpec{
+				Namespace: "production",
+			},
+		},
+		Status: v1.ClusterTokenStatus{
+			ManagedSecret: v1.ManagedSecret{
+				Name: "test-cluster-token",
+			},
+			TargetNamespaces: []string{"team-a", "team-b"},
+		},
+	}
+
+	if !token.UpdateManagedSecret() {
+		t.Fatal("UpdateManagedSecret() changed = false, want true")
+	}
+	if got := token.GetStatusTargetNamespaces(); got != nil {
+		t.Errorf("TargetNamespaces = %v, want nil after switching to a single namespace", got)
+	}
+}
+
 func TestClusterToken_SetStatusCondition(t *testing.T) {
 	tests := []struct {
 		name              string
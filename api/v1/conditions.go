@@ -9,11 +9,19 @@ const (
 	// spec.validateKey is false.
 	ConditionTypeKeyValid = "KeyValid"
 
+	// ConditionTypeInstallationsListed is set on an App once its Ready
+	// client is built and reports whether status.installations could be
+	// refreshed from GitHub.
+	ConditionTypeInstallationsListed = "InstallationsListed"
+
 	// Condition reasons used by the Token and ClusterToken controllers when
 	// resolving spec.appRef.
 
 	// ReasonAppNotFound indicates the referenced App does not exist.
 	ReasonAppNotFound = "AppNotFound"
+	// ReasonGrantMissing indicates a Token references an App in another
+	// namespace that no AppGrant permits it to use.
+	ReasonGrantMissing = "GrantMissing"
 	// ReasonAppNotReady indicates the referenced App exists but its Ready
 	// condition is not True.
 	ReasonAppNotReady = "AppNotReady"
@@ -30,4 +38,53 @@ const (
 	// ReasonInvalidKey indicates the resolved key material is missing,
 	// empty, or not a usable PEM-encoded RSA private key.
 	ReasonInvalidKey = "InvalidKey"
+	// ReasonCABundleInvalid indicates the ConfigMap or Secret named by
+	// spec.caBundleRef could not be fetched, or that the CA bundle contains
+	// no usable PEM-encoded certificates.
+	ReasonCABundleInvalid = "CABundleInvalid"
+	// ReasonListFailed indicates listing an App's installations failed.
+	ReasonListFailed = "ListFailed"
+	// ReasonInstallationNotFound indicates an installation selector matched
+	// no installation of the GitHub App.
+	ReasonInstallationNotFound = "InstallationNotFound"
+
+	// ReasonNoTargets indicates no namespace matching a ClusterToken's
+	// spec.secret.namespaceSelector can take a copy of its Secret, so no
+	// token is minted.
+	ReasonNoTargets = "NoTargets"
+
+	// ReasonTemplateError indicates spec.secret.template failed to parse or
+	// render, so no Secret data could be produced.
+	ReasonTemplateError = "TemplateError"
+
+	// ReasonPolicyViolation indicates a Token requests more than an
+	// applicable TokenPolicy or ClusterTokenPolicy allows.
+	ReasonPolicyViolation = "PolicyViolation"
+
+	// ReasonPermissionsExceeded indicates a Token requests permissions that
+	// the GitHub App installation it targets has not been granted.
+	ReasonPermissionsExceeded = "PermissionsExceeded"
+
+	// ReasonRepositorySelectionFailed indicates spec.repositorySelector
+	// could not be resolved, matched no repositories, or matched more than
+	// a token can be limited to.
+	ReasonRepositorySelectionFailed = "RepositorySelectionFailed"
+
+	// Condition reasons for GitHub rejecting a request for an installation
+	// token. ReasonInstallationNotFound is also used when the installation
+	// the token is minted for no longer exists.
+
+	// ReasonInvalidRepositories indicates GitHub rejected the repositories a
+	// token was limited to, typically because one does not exist or is not
+	// accessible to the installation.
+	ReasonInvalidRepositories = "InvalidRepositories"
+	// ReasonPermissionsRejected indicates GitHub refused to grant the
+	// permissions a token requested.
+	ReasonPermissionsRejected = "PermissionsRejected"
+	// ReasonRateLimited indicates the GitHub App exhausted its API rate
+	// limit, or tripped a secondary rate limit.
+	ReasonRateLimited = "RateLimited"
+	// ReasonAuthenticationFailed indicates GitHub rejected the GitHub App's
+	// credentials, typically because its private key was revoked.
+	ReasonAuthenticationFailed = "AuthenticationFailed"
 )
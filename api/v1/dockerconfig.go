@@ -0,0 +1,49 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import "slices"
+
+// DefaultDockerRegistry is always included in the auths map of a
+// dockerConfigJSON Secret.
+const DefaultDockerRegistry = "ghcr.io"
+
+// DockerConfigJSONSpec configures a 'kubernetes.io/dockerconfigjson' Secret
+// suitable for use in a Pod's imagePullSecrets.
+type DockerConfigJSONSpec struct {
+	// +optional
+	// +kubebuilder:validation:MaxItems:=16
+	// +kubebuilder:validation:items:MaxLength:=253
+	// +kubebuilder:validation:items:Pattern=`^([a-zA-Z0-9]([-a-zA-Z0-9]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([-a-zA-Z0-9]{0,61}[a-zA-Z0-9])?(:[0-9]{1,5})?$`
+	// +kubebuilder:example:={"containers.example.com"}
+	// Extra registry hosts, each with an optional port, to authenticate with
+	// the installation token in addition to ghcr.io (e.g. a GitHub Enterprise
+	// Server container registry)
+	ExtraRegistries []string `json:"extraRegistries,omitempty"`
+}
+
+// Registries returns ghcr.io followed by any extra registries, without
+// duplicates.
+func (d *DockerConfigJSONSpec) Registries() []string {
+	registries := []string{DefaultDockerRegistry}
+	for _, registry := range d.ExtraRegistries {
+		if !slices.Contains(registries, registry) {
+			registries = append(registries, registry)
+		}
+	}
+	return registries
+}
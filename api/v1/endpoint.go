@@ -0,0 +1,111 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// GitHubEndpoint selects the GitHub instance an App is registered with and
+// how the operator reaches it. It is inlined into AppSpec and ClusterAppSpec;
+// leaving it empty targets github.com directly, trusting the system roots.
+// It applies to every request made for the App, including token mints,
+// whichever provider holds its key.
+type GitHubEndpoint struct {
+	// +optional
+	// +kubebuilder:validation:MaxLength:=2048
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +kubebuilder:example:="https://github.example.com/api/v3/"
+	// REST API base URL of a GitHub Enterprise Server instance. A bare host
+	// URL such as "https://github.example.com" has "/api/v3/" appended.
+	// Leave unset for github.com.
+	BaseURL string `json:"baseURL,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:MaxLength:=2048
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// Uploads API URL of a GitHub Enterprise Server instance. Defaults to
+	// baseURL, with "/api/uploads/" appended to a bare host URL.
+	UploadURL string `json:"uploadURL,omitempty"`
+
+	// +optional
+	// PEM-encoded CA certificates to trust in addition to the system roots
+	// when talking to GitHub, e.g. for a TLS-inspecting egress proxy or a
+	// privately signed GitHub Enterprise Server. Mutually exclusive with
+	// caBundleRef.
+	CABundle string `json:"caBundle,omitempty"`
+
+	// +optional
+	// Reference to a ConfigMap or Secret key holding PEM-encoded CA
+	// certificates, used as for caBundle. Mutually exclusive with caBundle.
+	CABundleRef *CABundleReference `json:"caBundleRef,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:MaxLength:=2048
+	// +kubebuilder:validation:Pattern=`^(https?|socks5)://`
+	// +kubebuilder:example:="http://proxy.example.com:3128"
+	// URL of the HTTP(S) or SOCKS5 proxy through which GitHub is reached.
+	// Overrides the operator's HTTPS_PROXY environment for this App.
+	ProxyURL string `json:"proxyURL,omitempty"`
+}
+
+const (
+	// CABundleKindConfigMap selects a ConfigMap as the source of a CA bundle.
+	CABundleKindConfigMap = "ConfigMap"
+	// CABundleKindSecret selects a Secret as the source of a CA bundle.
+	CABundleKindSecret = "Secret"
+)
+
+// CABundleReference identifies a key in a ConfigMap or Secret holding
+// PEM-encoded CA certificates.
+type CABundleReference struct {
+	// +optional
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +kubebuilder:default:=ConfigMap
+	// Kind of the referenced resource: ConfigMap (default) or Secret.
+	Kind string `json:"kind,omitempty"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength:=253
+	// Name of the ConfigMap or Secret.
+	Name string `json:"name"`
+
+	// +optional
+	// +kubebuilder:validation:MaxLength:=253
+	// Namespace of the ConfigMap or Secret. Required on a ClusterApp; must
+	// be unset on an App, which may only reference its own namespace.
+	Namespace string `json:"namespace,omitempty"`
+
+	// +optional
+	// +kubebuilder:default:="ca.crt"
+	// Key within the resource's data holding the CA bundle. Defaults to
+	// "ca.crt".
+	Key string `json:"key,omitempty"`
+}
+
+// GetBaseURL returns the GitHub API base URL, or "" for github.com.
+func (e *GitHubEndpoint) GetBaseURL() string {
+	return e.BaseURL
+}
+
+// GetUploadURL returns the GitHub uploads URL, or "" to derive it from the
+// base URL.
+func (e *GitHubEndpoint) GetUploadURL() string {
+	return e.UploadURL
+}
+
+// GetProxyURL returns the proxy URL, or "" to use the operator's
+// environment.
+func (e *GitHubEndpoint) GetProxyURL() string {
+	return e.ProxyURL
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FailureStatus records the reconciles of an object that have failed since
+// it last reconciled successfully, from which the delay before it is retried
+// is computed.
+type FailureStatus struct {
+	// +optional
+	// Number of reconciles in a row that have failed, reset to zero by the
+	// next successful one
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+	// +optional
+	// Time of the most recent failed reconcile
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+}
+
+// RecordFailure counts a reconcile that failed at now, returning the number
+// of failures in a row.
+func (f *FailureStatus) RecordFailure(now time.Time) int32 {
+	f.ConsecutiveFailures++
+	lastFailure := metav1.NewTime(now)
+	f.LastFailureTime = &lastFailure
+	return f.ConsecutiveFailures
+}
+
+// ResetFailures clears the failure count after a successful reconcile,
+// reporting whether it changed. The time of the last failure is kept.
+func (f *FailureStatus) ResetFailures() (changed bool) {
+	if f.ConsecutiveFailures == 0 {
+		return false
+	}
+	f.ConsecutiveFailures = 0
+	return true
+}
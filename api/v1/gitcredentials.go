@@ -0,0 +1,59 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// GitFormat names a git credential file format that a managed Secret may
+// carry alongside its primary data.
+// +kubebuilder:validation:Enum=netrc;git-credentials;gitconfig
+type GitFormat string
+
+const (
+	// GitFormatNetrc writes a '.netrc' entry for the git host.
+	GitFormatNetrc GitFormat = "netrc"
+	// GitFormatGitCredentials writes a '.git-credentials' file for git's
+	// "store" credential helper.
+	GitFormatGitCredentials GitFormat = "git-credentials"
+	// GitFormatGitconfig writes a '.gitconfig' with url.insteadOf rewrites
+	// embedding the token.
+	GitFormatGitconfig GitFormat = "gitconfig"
+)
+
+// DefaultGitHost is the host git credential files are written for when
+// spec.secret.gitHost is unset.
+const DefaultGitHost = "github.com"
+
+// DataKey returns the Secret data key under which the format is written.
+func (f GitFormat) DataKey() string {
+	switch f {
+	case GitFormatNetrc:
+		return ".netrc"
+	case GitFormatGitCredentials:
+		return ".git-credentials"
+	case GitFormatGitconfig:
+		return ".gitconfig"
+	default:
+		return ""
+	}
+}
+
+// gitHost returns host, or [DefaultGitHost] when empty.
+func gitHost(host string) string {
+	if host == "" {
+		return DefaultGitHost
+	}
+	return host
+}
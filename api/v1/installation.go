@@ -0,0 +1,85 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// InstallationSelector identifies a GitHub App installation by the account
+// it is installed on, or by a repository it can access, rather than by its
+// numeric ID, which differs between otherwise identical Apps.
+//
+// +kubebuilder:validation:XValidation:rule="has(self.account) != has(self.repository)",message="exactly one of account and repository must be set"
+type InstallationSelector struct {
+	// +optional
+	// +kubebuilder:validation:MaxLength:=39
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9][-a-zA-Z0-9]*$`
+	// +kubebuilder:example:="my-org"
+	// Login of the organization or user account the App is installed on.
+	Account string `json:"account,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:MaxLength:=140
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9][-a-zA-Z0-9]*/[-._a-zA-Z0-9]+$`
+	// +kubebuilder:example:="my-org/my-repo"
+	// Repository, as "owner/name", whose installation to use.
+	Repository string `json:"repository,omitempty"`
+}
+
+// String returns the account login, or the repository as "owner/name".
+func (s *InstallationSelector) String() string {
+	if s == nil {
+		return ""
+	}
+	if s.Repository != "" {
+		return s.Repository
+	}
+	return s.Account
+}
+
+// ResolvedInstallation records the installation ID an App's
+// InstallationSelector last resolved to.
+type ResolvedInstallation struct {
+	// Selector that was resolved: an account login, or a repository as
+	// "owner/name".
+	Selector string `json:"selector"`
+
+	// ID of the installation.
+	ID int64 `json:"id"`
+}
+
+// InstallationStatus describes one installation of a GitHub App, as last
+// observed by the App reconciler.
+type InstallationStatus struct {
+	// ID of the installation.
+	ID int64 `json:"id"`
+
+	// Login of the organization or user account the App is installed on.
+	Account string `json:"account"`
+
+	// +optional
+	// Whether the installation covers "all" repositories of the account or
+	// only "selected" ones.
+	RepositorySelection string `json:"repositorySelection,omitempty"`
+
+	// +optional
+	// Permissions granted to the installation, by name (e.g. "contents")
+	// and level (read, write or admin).
+	Permissions map[string]string `json:"permissions,omitempty"`
+
+	// +optional
+	// Whether the installation is suspended, in which case no tokens can be
+	// minted for it.
+	Suspended bool `json:"suspended,omitempty"`
+}
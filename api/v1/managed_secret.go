@@ -1,25 +1,51 @@
 package v1
 
-import "k8s.io/apimachinery/pkg/types"
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Secret types written by the operator for its managed Secrets.
+const (
+	SecretTypeToken     = corev1.SecretType("github.as-code.io/token")
+	SecretTypeBasicAuth = corev1.SecretType("github.as-code.io/basic-auth")
+	SecretTypeTemplate  = corev1.SecretTypeOpaque
+	SecretTypeDocker    = corev1.SecretTypeDockerConfigJson
+	SecretTypeArgoCD    = corev1.SecretTypeOpaque
+)
 
 type secretOwner interface {
 	GetSecretNamespace() string
 	GetSecretName() string
 	GetSecretBasicAuth() bool
+	GetSecretType() corev1.SecretType
 }
 
 type ManagedSecret struct {
-	BasicAuth bool   `json:"basicAuth"`
-	Namespace string `json:"namespace,omitempty"`
-	Name      string `json:"name,omitempty"`
+	BasicAuth bool              `json:"basicAuth"`
+	Type      corev1.SecretType `json:"type,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name,omitempty"`
 }
 
 func (m ManagedSecret) IsUnset() bool {
 	return m.Name == ""
 }
 
+// SecretType returns the type of the managed Secret. Status written before
+// the type was recorded carries only BasicAuth, from which the type follows.
+func (m ManagedSecret) SecretType() corev1.SecretType {
+	if m.Type != "" {
+		return m.Type
+	}
+	if m.BasicAuth {
+		return SecretTypeBasicAuth
+	}
+	return SecretTypeToken
+}
+
 func (m ManagedSecret) MatchesSpec(owner secretOwner) bool {
-	return m.Namespace == owner.GetSecretNamespace() && m.Name == owner.GetSecretName() && m.BasicAuth == owner.GetSecretBasicAuth()
+	return m.Namespace == owner.GetSecretNamespace() && m.Name == owner.GetSecretName() && m.BasicAuth == owner.GetSecretBasicAuth() && m.SecretType() == owner.GetSecretType()
 }
 
 func (m ManagedSecret) Key() types.NamespacedName {
@@ -28,3 +54,21 @@ func (m ManagedSecret) Key() types.NamespacedName {
 		Name:      m.Name,
 	}
 }
+
+// secretType returns the Secret type implied by a Token or ClusterToken
+// secret spec. Secret types are immutable, so a change here forces the
+// managed Secret to be recreated.
+func secretType(basicAuth bool, template map[string]string, dockerConfigJSON *DockerConfigJSONSpec, argoCD *ArgoCDSpec) corev1.SecretType {
+	switch {
+	case dockerConfigJSON != nil:
+		return SecretTypeDocker
+	case argoCD != nil:
+		return SecretTypeArgoCD
+	case len(template) > 0:
+		return SecretTypeTemplate
+	case basicAuth:
+		return SecretTypeBasicAuth
+	default:
+		return SecretTypeToken
+	}
+}
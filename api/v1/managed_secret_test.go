@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	v1 "github.com/isometry/github-token-manager/api/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
@@ -114,6 +115,34 @@ func TestManagedSecret_MatchesSpec(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "legacy status without type matches",
+			secret: v1.ManagedSecret{
+				Namespace: "default",
+				Name:      "my-secret",
+				BasicAuth: true,
+			},
+			owner: &mockSecretOwner{
+				namespace: "default",
+				name:      "my-secret",
+				basicAuth: true,
+			},
+			want: true,
+		},
+		{
+			name: "type mismatch",
+			secret: v1.ManagedSecret{
+				Namespace: "default",
+				Name:      "my-secret",
+				Type:      v1.SecretTypeToken,
+			},
+			owner: &mockSecretOwner{
+				namespace:  "default",
+				name:       "my-secret",
+				secretType: v1.SecretTypeTemplate,
+			},
+			want: false,
+		},
 		{
 			name:   "empty secret does not match",
 			secret: v1.ManagedSecret{},
@@ -181,9 +210,10 @@ func TestManagedSecret_Key(t *testing.T) {
 
 // mockSecretOwner implements the secretOwner interface for testing
 type mockSecretOwner struct {
-	namespace string
-	name      string
-	basicAuth bool
+	namespace  string
+	name       string
+	basicAuth  bool
+	secretType corev1.SecretType
 }
 
 func (m *mockSecretOwner) GetSecretNamespace() string {
@@ -197,3 +227,13 @@ func (m *mockSecretOwner) GetSecretName() string {
 func (m *mockSecretOwner) GetSecretBasicAuth() bool {
 	return m.basicAuth
 }
+
+func (m *mockSecretOwner) GetSecretType() corev1.SecretType {
+	if m.secretType != "" {
+		return m.secretType
+	}
+	if m.basicAuth {
+		return v1.SecretTypeBasicAuth
+	}
+	return v1.SecretTypeToken
+}
@@ -3,6 +3,8 @@ package v1
 import (
 	"github.com/google/go-github/v84/github"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/isometry/github-token-manager/internal/ghapp"
 )
 
 type InstallationAccessToken struct {
@@ -10,6 +12,51 @@ type InstallationAccessToken struct {
 	ExpiresAt metav1.Time `json:"expiresAt,omitempty"`
 }
 
+// TokenGrant records the access GitHub actually granted to the installation
+// token held in the Secret, which may be narrower than requested.
+type TokenGrant struct {
+	// +optional
+	// Permissions granted to the token, by name (e.g. "contents") and level
+	// (read, write or admin).
+	Permissions map[string]string `json:"permissions,omitempty"`
+
+	// +optional
+	// Whether the token covers "all" repositories of the installation or
+	// only "selected" ones.
+	RepositorySelection string `json:"repositorySelection,omitempty"`
+
+	// +optional
+	// Full names of the repositories the token covers, when limited to
+	// selected repositories.
+	Repositories []string `json:"repositories,omitempty"`
+
+	// +optional
+	// Number of entries in repositories.
+	RepositoryCount int32 `json:"repositoryCount,omitempty"`
+}
+
+// NewTokenGrant returns the grant recorded in an installation token response.
+// GitHub lists repositories only for a token limited to selected ones, so the
+// selection is derived from their presence.
+func NewTokenGrant(token *github.InstallationToken) TokenGrant {
+	grant := TokenGrant{
+		Permissions:         ghapp.PermissionMap(token.GetPermissions()),
+		RepositorySelection: "all",
+	}
+	for _, repo := range token.Repositories {
+		name := repo.GetFullName()
+		if name == "" {
+			name = repo.GetName()
+		}
+		grant.Repositories = append(grant.Repositories, name)
+	}
+	if len(grant.Repositories) > 0 {
+		grant.RepositorySelection = "selected"
+	}
+	grant.RepositoryCount = int32(len(grant.Repositories))
+	return grant
+}
+
 type Permissions struct {
 	// +optional
 	// +kubebuilder:validation:Enum:=read;write
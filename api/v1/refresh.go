@@ -0,0 +1,43 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/isometry/github-token-manager/internal/ghapp"
+)
+
+// refreshWindow returns window if set, else the part of a token's validity
+// left once refreshInterval has elapsed since it was minted.
+func refreshWindow(window *metav1.Duration, refreshInterval time.Duration) time.Duration {
+	if window != nil {
+		return window.Duration
+	}
+	return max(ghapp.TokenValidity-refreshInterval, 0)
+}
+
+// refreshJitter returns jitter if set, else a tenth of the time between
+// minting a token and refreshing it.
+func refreshJitter(jitter *metav1.Duration, window time.Duration) time.Duration {
+	if jitter != nil {
+		return jitter.Duration
+	}
+	return max(ghapp.TokenValidity-window, 0) / 10
+}
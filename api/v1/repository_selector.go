@@ -0,0 +1,87 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"path"
+	"slices"
+)
+
+// MaxTokenRepositories is the most repositories GitHub allows a single
+// installation token to be limited to.
+const MaxTokenRepositories = 500
+
+// RepositorySelector selects the repositories a token is limited to from
+// those accessible to the installation, instead of listing them. A
+// repository is selected when it matches every criterion that is set.
+//
+// +kubebuilder:validation:XValidation:rule="has(self.names) || has(self.topics) || has(self.customProperties)",message="at least one of names, topics and customProperties must be set"
+type RepositorySelector struct {
+	// +optional
+	// +kubebuilder:validation:MaxItems:=64
+	// +kubebuilder:example:={"service-*", "library-?"}
+	// Glob patterns, as understood by Go's path.Match, of which a
+	// repository's name (without its owner) must match at least one.
+	Names []string `json:"names,omitempty"`
+
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:MaxItems:=20
+	// +kubebuilder:example:={"team-platform"}
+	// GitHub topics a repository must all carry.
+	Topics []string `json:"topics,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:MaxProperties:=20
+	// +kubebuilder:example:={"environment": "production"}
+	// Values the repository's custom properties must all hold. A
+	// multi-select property matches when the value is among those selected.
+	// Requires the installation to have been granted read access to the
+	// organization's custom properties.
+	CustomProperties map[string]string `json:"customProperties,omitempty"`
+}
+
+// Matches reports whether a repository with the given name (without its
+// owner), topics and custom property values is selected.
+func (s *RepositorySelector) Matches(name string, topics []string, properties map[string][]string) bool {
+	if s == nil {
+		return false
+	}
+	if len(s.Names) > 0 && !slices.ContainsFunc(s.Names, func(pattern string) bool {
+		matched, err := path.Match(pattern, name)
+		return err == nil && matched
+	}) {
+		return false
+	}
+	for _, topic := range s.Topics {
+		if !slices.Contains(topics, topic) {
+			return false
+		}
+	}
+	for property, value := range s.CustomProperties {
+		if !slices.Contains(properties[property], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// NeedsCustomProperties reports whether matching requires the custom
+// property values of repositories.
+func (s *RepositorySelector) NeedsCustomProperties() bool {
+	return s != nil && len(s.CustomProperties) > 0
+}
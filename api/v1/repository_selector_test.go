@@ -0,0 +1,83 @@
+package v1_test
+
+import (
+	"testing"
+
+	v1 "github.com/isometry/github-token-manager/api/v1"
+)
+
+func TestRepositorySelector_Matches(t *testing.T) {
+	topics := []string{"go", "team-platform"}
+	properties := map[string][]string{
+		"environment": {"production"},
+		"regions":     {"eu", "us"},
+	}
+
+	tests := []struct {
+		name     string
+		selector *v1.RepositorySelector
+		repo     string
+		want     bool
+	}{
+		{
+			name: "nil selector",
+			repo: "service-a",
+		},
+		{
+			name:     "name glob",
+			selector: &v1.RepositorySelector{Names: []string{"library-*", "service-*"}},
+			repo:     "service-a",
+			want:     true,
+		},
+		{
+			name:     "name glob mismatch",
+			selector: &v1.RepositorySelector{Names: []string{"library-*"}},
+			repo:     "service-a",
+		},
+		{
+			name:     "all topics",
+			selector: &v1.RepositorySelector{Topics: []string{"go", "team-platform"}},
+			repo:     "service-a",
+			want:     true,
+		},
+		{
+			name:     "missing topic",
+			selector: &v1.RepositorySelector{Topics: []string{"go", "team-data"}},
+			repo:     "service-a",
+		},
+		{
+			name:     "custom property",
+			selector: &v1.RepositorySelector{CustomProperties: map[string]string{"environment": "production"}},
+			repo:     "service-a",
+			want:     true,
+		},
+		{
+			name:     "multi-select custom property",
+			selector: &v1.RepositorySelector{CustomProperties: map[string]string{"regions": "us"}},
+			repo:     "service-a",
+			want:     true,
+		},
+		{
+			name:     "custom property mismatch",
+			selector: &v1.RepositorySelector{CustomProperties: map[string]string{"environment": "staging"}},
+			repo:     "service-a",
+		},
+		{
+			name: "every criterion must match",
+			selector: &v1.RepositorySelector{
+				Names:            []string{"service-*"},
+				Topics:           []string{"go"},
+				CustomProperties: map[string]string{"environment": "staging"},
+			},
+			repo: "service-a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.selector.Matches(tt.repo, topics, properties); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.repo, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package v1_test
 
 import (
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/google/go-github/v84/github"
 	v1 "github.com/isometry/github-token-manager/api/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -225,6 +227,85 @@ func TestToken_UpdateManagedSecret(t *testing.T) {
 	}
 }
 
+func TestToken_GetSecretType(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret v1.TokenSecretSpec
+		want   corev1.SecretType
+	}{
+		{
+			name:   "default token",
+			secret: v1.TokenSecretSpec{},
+			want:   v1.SecretTypeToken,
+		},
+		{
+			name:   "basic auth",
+			secret: v1.TokenSecretSpec{BasicAuth: true},
+			want:   v1.SecretTypeBasicAuth,
+		},
+		{
+			name: "template",
+			secret: v1.TokenSecretSpec{
+				Template: map[string]string{"GITHUB_TOKEN": "{{ .Token }}"},
+			},
+			want: v1.SecretTypeTemplate,
+		},
+		{
+			name:   "docker config json",
+			secret: v1.TokenSecretSpec{DockerConfigJSON: &v1.DockerConfigJSONSpec{}},
+			want:   corev1.SecretTypeDockerConfigJson,
+		},
+		{
+			name:   "argo cd",
+			secret: v1.TokenSecretSpec{ArgoCD: &v1.ArgoCDSpec{}},
+			want:   corev1.SecretTypeOpaque,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &v1.Token{Spec: v1.TokenSpec{Secret: tt.secret}}
+			if got := token.GetSecretType(); got != tt.want {
+				t.Errorf("GetSecretType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerConfigJSONSpec_Registries(t *testing.T) {
+	spec := &v1.DockerConfigJSONSpec{
+		ExtraRegistries: []string{"containers.example.com", "ghcr.io", "containers.example.com"},
+	}
+	got := spec.Registries()
+	want := []string{"ghcr.io", "containers.example.com"}
+	if len(got) != len(want) {
+		t.Fatalf("Registries() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Registries()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestArgoCDSpec_Defaults(t *testing.T) {
+	spec := &v1.ArgoCDSpec{}
+	if got := spec.GetSecretType(); got != "repo-creds" {
+		t.Errorf("GetSecretType() = %v, want repo-creds", got)
+	}
+	if got := spec.GetURL(); got != v1.DefaultArgoCDURL {
+		t.Errorf("GetURL() = %v, want %v", got, v1.DefaultArgoCDURL)
+	}
+
+	spec = &v1.ArgoCDSpec{SecretType: "repository", URL: "https://github.com/my-org/my-repo"}
+	if got := spec.GetSecretType(); got != "repository" {
+		t.Errorf("GetSecretType() = %v, want repository", got)
+	}
+	if got := spec.GetURL(); got != "https://github.com/my-org/my-repo" {
+		t.Errorf("GetURL() = %v, want explicit URL", got)
+	}
+}
+
 func TestToken_SetStatusCondition(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -322,6 +403,33 @@ func TestToken_GetStatusTimestamps(t *testing.T) {
 	}
 }
 
+func TestToken_SetStatusGrant(t *testing.T) {
+	token := &v1.Token{}
+	token.SetStatusGrant(&github.InstallationToken{
+		Permissions: &github.InstallationPermissions{Contents: github.Ptr("read")},
+		Repositories: []*github.Repository{
+			{Name: github.Ptr("widgets"), FullName: github.Ptr("acme/widgets")},
+			{Name: github.Ptr("gadgets")},
+		},
+	})
+
+	grant := token.Status.TokenGrant
+	if grant.Permissions["contents"] != "read" || len(grant.Permissions) != 1 {
+		t.Errorf("Permissions = %v, want contents=read", grant.Permissions)
+	}
+	if grant.RepositorySelection != "selected" || grant.RepositoryCount != 2 {
+		t.Errorf("RepositorySelection, RepositoryCount = %q, %d; want selected, 2", grant.RepositorySelection, grant.RepositoryCount)
+	}
+	if want := []string{"acme/widgets", "gadgets"}; !slices.Equal(grant.Repositories, want) {
+		t.Errorf("Repositories = %v, want %v", grant.Repositories, want)
+	}
+
+	token.SetStatusGrant(&github.InstallationToken{})
+	if grant := token.Status.TokenGrant; grant.RepositorySelection != "all" || grant.Repositories != nil {
+		t.Errorf("unrestricted grant = %+v, want all repositories", grant)
+	}
+}
+
 func TestToken_SetStatusTimestamps(t *testing.T) {
 	expiresAt := time.Now().Add(1 * time.Hour)
 
@@ -343,3 +451,48 @@ func TestToken_SetStatusTimestamps(t *testing.T) {
 		t.Error("CreatedAt should be before ExpiresAt")
 	}
 }
+
+func TestToken_GetRefreshWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       v1.TokenSpec
+		wantWindow time.Duration
+		wantJitter time.Duration
+	}{
+		{
+			name:       "derived from refresh interval",
+			spec:       v1.TokenSpec{RefreshInterval: metav1.Duration{Duration: 30 * time.Minute}},
+			wantWindow: 30 * time.Minute,
+			wantJitter: 3 * time.Minute,
+		},
+		{
+			name: "explicit window",
+			spec: v1.TokenSpec{
+				RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+				RefreshWindow:   &metav1.Duration{Duration: 10 * time.Minute},
+			},
+			wantWindow: 10 * time.Minute,
+			wantJitter: 5 * time.Minute,
+		},
+		{
+			name: "explicit jitter",
+			spec: v1.TokenSpec{
+				RefreshWindow: &metav1.Duration{Duration: 10 * time.Minute},
+				RefreshJitter: &metav1.Duration{},
+			},
+			wantWindow: 10 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &v1.Token{Spec: tt.spec}
+			if got := token.GetRefreshWindow(); got != tt.wantWindow {
+				t.Errorf("GetRefreshWindow() = %v, want %v", got, tt.wantWindow)
+			}
+			if got := token.GetRefreshJitter(); got != tt.wantJitter {
+				t.Errorf("GetRefreshJitter() = %v, want %v", got, tt.wantJitter)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TokenPolicyRules caps what a Token may request. Every field is optional and
+// an unset field imposes no limit; when several policies apply to a Token it
+// must satisfy all of them.
+type TokenPolicyRules struct {
+	// +optional
+	// +kubebuilder:example:={"metadata": "read", "contents": "write"}
+	// Highest permission levels a Token may request. Permissions not listed
+	// may not be requested at all, and a Token that omits spec.permissions
+	// (inheriting every permission of the GitHub App) is rejected.
+	Permissions *Permissions `json:"permissions,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:MaxItems:=500
+	// +kubebuilder:example:={"infra-*", "docs"}
+	// Repository names, or path.Match globs over them, a Token may request.
+	// A Token that omits spec.repositories (covering every repository of the
+	// installation) or sets spec.repositoryIDs is rejected.
+	Repositories []string `json:"repositories,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:MaxItems:=64
+	// +kubebuilder:example:={"team-app"}
+	// Names, or path.Match globs over them, of the Apps a Token may
+	// reference through spec.appRef. Apps in another namespace, shared by
+	// an AppGrant, are matched as "namespace/name".
+	Apps []string `json:"apps,omitempty"`
+
+	// +optional
+	// Allow Tokens without spec.appRef to use the operator's startup GitHub
+	// App when apps is set. Ignored when apps is unset.
+	AllowStartupApp bool `json:"allowStartupApp,omitempty"`
+}
+
+// TokenPolicySpec defines the limits for Tokens in the policy's namespace.
+type TokenPolicySpec struct {
+	TokenPolicyRules `json:",inline"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced,shortName=tp,path=tokenpolicies
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// TokenPolicy limits the permissions, repositories and Apps that Tokens in
+// its namespace may request.
+type TokenPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec TokenPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TokenPolicyList contains a list of TokenPolicy.
+type TokenPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []TokenPolicy `json:"items"`
+}
+
+// ClusterTokenPolicySpec defines the limits for Tokens in every namespace
+// matching the policy's namespaceSelector.
+type ClusterTokenPolicySpec struct {
+	// +optional
+	// Namespaces whose Tokens this policy applies to (defaults to all
+	// namespaces)
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	TokenPolicyRules `json:",inline"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=ctp,path=clustertokenpolicies
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterTokenPolicy limits the permissions, repositories and Apps that
+// Tokens in the namespaces it selects may request.
+type ClusterTokenPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterTokenPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterTokenPolicyList contains a list of ClusterTokenPolicy.
+type ClusterTokenPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ClusterTokenPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TokenPolicy{}, &TokenPolicyList{}, &ClusterTokenPolicy{}, &ClusterTokenPolicyList{})
+}
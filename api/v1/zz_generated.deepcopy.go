@@ -52,6 +52,94 @@ func (in *App) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppGrant) DeepCopyInto(out *AppGrant) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppGrant.
+func (in *AppGrant) DeepCopy() *AppGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(AppGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AppGrant) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppGrantList) DeepCopyInto(out *AppGrantList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AppGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppGrantList.
+func (in *AppGrantList) DeepCopy() *AppGrantList {
+	if in == nil {
+		return nil
+	}
+	out := new(AppGrantList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AppGrantList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppGrantSpec) DeepCopyInto(out *AppGrantSpec) {
+	*out = *in
+	if in.Apps != nil {
+		in, out := &in.Apps, &out.Apps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppGrantSpec.
+func (in *AppGrantSpec) DeepCopy() *AppGrantSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AppGrantSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AppList) DeepCopyInto(out *AppList) {
 	*out = *in
@@ -102,11 +190,17 @@ func (in *AppReference) DeepCopy() *AppReference {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AppSpec) DeepCopyInto(out *AppSpec) {
 	*out = *in
+	if in.Installation != nil {
+		in, out := &in.Installation, &out.Installation
+		*out = new(InstallationSelector)
+		**out = **in
+	}
 	if in.KeyRef != nil {
 		in, out := &in.KeyRef, &out.KeyRef
 		*out = new(KeySecretReference)
 		**out = **in
 	}
+	in.GitHubEndpoint.DeepCopyInto(&out.GitHubEndpoint)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppSpec.
@@ -122,6 +216,19 @@ func (in *AppSpec) DeepCopy() *AppSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AppStatus) DeepCopyInto(out *AppStatus) {
 	*out = *in
+	if in.Installation != nil {
+		in, out := &in.Installation, &out.Installation
+		*out = new(ResolvedInstallation)
+		**out = **in
+	}
+	if in.Installations != nil {
+		in, out := &in.Installations, &out.Installations
+		*out = make([]InstallationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.FailureStatus.DeepCopyInto(&out.FailureStatus)
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -141,6 +248,136 @@ func (in *AppStatus) DeepCopy() *AppStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDSpec) DeepCopyInto(out *ArgoCDSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDSpec.
+func (in *ArgoCDSpec) DeepCopy() *ArgoCDSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CABundleReference) DeepCopyInto(out *CABundleReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CABundleReference.
+func (in *CABundleReference) DeepCopy() *CABundleReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CABundleReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterApp) DeepCopyInto(out *ClusterApp) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterApp.
+func (in *ClusterApp) DeepCopy() *ClusterApp {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterApp)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterApp) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAppList) DeepCopyInto(out *ClusterAppList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterApp, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAppList.
+func (in *ClusterAppList) DeepCopy() *ClusterAppList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAppList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterAppList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAppSpec) DeepCopyInto(out *ClusterAppSpec) {
+	*out = *in
+	if in.Installation != nil {
+		in, out := &in.Installation, &out.Installation
+		*out = new(InstallationSelector)
+		**out = **in
+	}
+	if in.KeyRef != nil {
+		in, out := &in.KeyRef, &out.KeyRef
+		*out = new(ClusterKeySecretReference)
+		**out = **in
+	}
+	in.GitHubEndpoint.DeepCopyInto(&out.GitHubEndpoint)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAppSpec.
+func (in *ClusterAppSpec) DeepCopy() *ClusterAppSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAppSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterKeySecretReference) DeepCopyInto(out *ClusterKeySecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterKeySecretReference.
+func (in *ClusterKeySecretReference) DeepCopy() *ClusterKeySecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterKeySecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterToken) DeepCopyInto(out *ClusterToken) {
 	*out = *in
@@ -200,9 +437,93 @@ func (in *ClusterTokenList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterTokenPolicy) DeepCopyInto(out *ClusterTokenPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTokenPolicy.
+func (in *ClusterTokenPolicy) DeepCopy() *ClusterTokenPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterTokenPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterTokenPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterTokenPolicyList) DeepCopyInto(out *ClusterTokenPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterTokenPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTokenPolicyList.
+func (in *ClusterTokenPolicyList) DeepCopy() *ClusterTokenPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterTokenPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterTokenPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterTokenPolicySpec) DeepCopyInto(out *ClusterTokenPolicySpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.TokenPolicyRules.DeepCopyInto(&out.TokenPolicyRules)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTokenPolicySpec.
+func (in *ClusterTokenPolicySpec) DeepCopy() *ClusterTokenPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterTokenPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterTokenSecretSpec) DeepCopyInto(out *ClusterTokenSecretSpec) {
 	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Labels != nil {
 		in, out := &in.Labels, &out.Labels
 		*out = make(map[string]string, len(*in))
@@ -217,6 +538,28 @@ func (in *ClusterTokenSecretSpec) DeepCopyInto(out *ClusterTokenSecretSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DockerConfigJSON != nil {
+		in, out := &in.DockerConfigJSON, &out.DockerConfigJSON
+		*out = new(DockerConfigJSONSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ArgoCD != nil {
+		in, out := &in.ArgoCD, &out.ArgoCD
+		*out = new(ArgoCDSpec)
+		**out = **in
+	}
+	if in.GitFormats != nil {
+		in, out := &in.GitFormats, &out.GitFormats
+		*out = make([]GitFormat, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTokenSecretSpec.
@@ -238,7 +581,22 @@ func (in *ClusterTokenSpec) DeepCopyInto(out *ClusterTokenSpec) {
 		**out = **in
 	}
 	in.Secret.DeepCopyInto(&out.Secret)
+	if in.Installation != nil {
+		in, out := &in.Installation, &out.Installation
+		*out = new(InstallationSelector)
+		**out = **in
+	}
 	out.RefreshInterval = in.RefreshInterval
+	if in.RefreshWindow != nil {
+		in, out := &in.RefreshWindow, &out.RefreshWindow
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RefreshJitter != nil {
+		in, out := &in.RefreshJitter, &out.RefreshJitter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	out.RetryInterval = in.RetryInterval
 	if in.Permissions != nil {
 		in, out := &in.Permissions, &out.Permissions
@@ -255,6 +613,11 @@ func (in *ClusterTokenSpec) DeepCopyInto(out *ClusterTokenSpec) {
 		*out = make([]int64, len(*in))
 		copy(*out, *in)
 	}
+	if in.RepositorySelector != nil {
+		in, out := &in.RepositorySelector, &out.RepositorySelector
+		*out = new(RepositorySelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTokenSpec.
@@ -271,7 +634,19 @@ func (in *ClusterTokenSpec) DeepCopy() *ClusterTokenSpec {
 func (in *ClusterTokenStatus) DeepCopyInto(out *ClusterTokenStatus) {
 	*out = *in
 	out.ManagedSecret = in.ManagedSecret
+	if in.TargetNamespaces != nil {
+		in, out := &in.TargetNamespaces, &out.TargetNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	in.IAT.DeepCopyInto(&out.IAT)
+	in.TokenGrant.DeepCopyInto(&out.TokenGrant)
+	if in.SelectedRepositories != nil {
+		in, out := &in.SelectedRepositories, &out.SelectedRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.FailureStatus.DeepCopyInto(&out.FailureStatus)
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -291,6 +666,65 @@ func (in *ClusterTokenStatus) DeepCopy() *ClusterTokenStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DockerConfigJSONSpec) DeepCopyInto(out *DockerConfigJSONSpec) {
+	*out = *in
+	if in.ExtraRegistries != nil {
+		in, out := &in.ExtraRegistries, &out.ExtraRegistries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DockerConfigJSONSpec.
+func (in *DockerConfigJSONSpec) DeepCopy() *DockerConfigJSONSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DockerConfigJSONSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailureStatus) DeepCopyInto(out *FailureStatus) {
+	*out = *in
+	if in.LastFailureTime != nil {
+		in, out := &in.LastFailureTime, &out.LastFailureTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureStatus.
+func (in *FailureStatus) DeepCopy() *FailureStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FailureStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubEndpoint) DeepCopyInto(out *GitHubEndpoint) {
+	*out = *in
+	if in.CABundleRef != nil {
+		in, out := &in.CABundleRef, &out.CABundleRef
+		*out = new(CABundleReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubEndpoint.
+func (in *GitHubEndpoint) DeepCopy() *GitHubEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstallationAccessToken) DeepCopyInto(out *InstallationAccessToken) {
 	*out = *in
@@ -308,6 +742,43 @@ func (in *InstallationAccessToken) DeepCopy() *InstallationAccessToken {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstallationSelector) DeepCopyInto(out *InstallationSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallationSelector.
+func (in *InstallationSelector) DeepCopy() *InstallationSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(InstallationSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstallationStatus) DeepCopyInto(out *InstallationStatus) {
+	*out = *in
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallationStatus.
+func (in *InstallationStatus) DeepCopy() *InstallationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstallationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeySecretReference) DeepCopyInto(out *KeySecretReference) {
 	*out = *in
@@ -548,6 +1019,53 @@ func (in *Permissions) DeepCopy() *Permissions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositorySelector) DeepCopyInto(out *RepositorySelector) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Topics != nil {
+		in, out := &in.Topics, &out.Topics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CustomProperties != nil {
+		in, out := &in.CustomProperties, &out.CustomProperties
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositorySelector.
+func (in *RepositorySelector) DeepCopy() *RepositorySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositorySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolvedInstallation) DeepCopyInto(out *ResolvedInstallation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolvedInstallation.
+func (in *ResolvedInstallation) DeepCopy() *ResolvedInstallation {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolvedInstallation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Token) DeepCopyInto(out *Token) {
 	*out = *in
@@ -575,6 +1093,33 @@ func (in *Token) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenGrant) DeepCopyInto(out *TokenGrant) {
+	*out = *in
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenGrant.
+func (in *TokenGrant) DeepCopy() *TokenGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TokenList) DeepCopyInto(out *TokenList) {
 	*out = *in
@@ -607,6 +1152,110 @@ func (in *TokenList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenPolicy) DeepCopyInto(out *TokenPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenPolicy.
+func (in *TokenPolicy) DeepCopy() *TokenPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TokenPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenPolicyList) DeepCopyInto(out *TokenPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TokenPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenPolicyList.
+func (in *TokenPolicyList) DeepCopy() *TokenPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TokenPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenPolicyRules) DeepCopyInto(out *TokenPolicyRules) {
+	*out = *in
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = new(Permissions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Apps != nil {
+		in, out := &in.Apps, &out.Apps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenPolicyRules.
+func (in *TokenPolicyRules) DeepCopy() *TokenPolicyRules {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenPolicyRules)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenPolicySpec) DeepCopyInto(out *TokenPolicySpec) {
+	*out = *in
+	in.TokenPolicyRules.DeepCopyInto(&out.TokenPolicyRules)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenPolicySpec.
+func (in *TokenPolicySpec) DeepCopy() *TokenPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TokenSecretSpec) DeepCopyInto(out *TokenSecretSpec) {
 	*out = *in
@@ -624,6 +1273,28 @@ func (in *TokenSecretSpec) DeepCopyInto(out *TokenSecretSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DockerConfigJSON != nil {
+		in, out := &in.DockerConfigJSON, &out.DockerConfigJSON
+		*out = new(DockerConfigJSONSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ArgoCD != nil {
+		in, out := &in.ArgoCD, &out.ArgoCD
+		*out = new(ArgoCDSpec)
+		**out = **in
+	}
+	if in.GitFormats != nil {
+		in, out := &in.GitFormats, &out.GitFormats
+		*out = make([]GitFormat, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenSecretSpec.
@@ -645,7 +1316,22 @@ func (in *TokenSpec) DeepCopyInto(out *TokenSpec) {
 		**out = **in
 	}
 	in.Secret.DeepCopyInto(&out.Secret)
+	if in.Installation != nil {
+		in, out := &in.Installation, &out.Installation
+		*out = new(InstallationSelector)
+		**out = **in
+	}
 	out.RefreshInterval = in.RefreshInterval
+	if in.RefreshWindow != nil {
+		in, out := &in.RefreshWindow, &out.RefreshWindow
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RefreshJitter != nil {
+		in, out := &in.RefreshJitter, &out.RefreshJitter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	out.RetryInterval = in.RetryInterval
 	if in.Permissions != nil {
 		in, out := &in.Permissions, &out.Permissions
@@ -662,6 +1348,11 @@ func (in *TokenSpec) DeepCopyInto(out *TokenSpec) {
 		*out = make([]int64, len(*in))
 		copy(*out, *in)
 	}
+	if in.RepositorySelector != nil {
+		in, out := &in.RepositorySelector, &out.RepositorySelector
+		*out = new(RepositorySelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenSpec.
@@ -679,6 +1370,13 @@ func (in *TokenStatus) DeepCopyInto(out *TokenStatus) {
 	*out = *in
 	out.ManagedSecret = in.ManagedSecret
 	in.IAT.DeepCopyInto(&out.IAT)
+	in.TokenGrant.DeepCopyInto(&out.TokenGrant)
+	if in.SelectedRepositories != nil {
+		in, out := &in.SelectedRepositories, &out.SelectedRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.FailureStatus.DeepCopyInto(&out.FailureStatus)
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
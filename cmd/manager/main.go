@@ -41,9 +41,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/backoff"
 	"github.com/isometry/github-token-manager/internal/controller"
+	"github.com/isometry/github-token-manager/internal/events"
 	"github.com/isometry/github-token-manager/internal/ghapp"
 	"github.com/isometry/github-token-manager/internal/metrics"
+	webhookv1 "github.com/isometry/github-token-manager/internal/webhook/v1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -66,9 +69,11 @@ func main() {
 	var metricsCertPath, metricsCertName, metricsCertKey string
 	var webhookCertPath, webhookCertName, webhookCertKey string
 	var enableLeaderElection bool
+	var enableWebhooks bool
 	var probeAddr string
 	var secureMetrics bool
 	var disableHTTP2 bool
+	var retryPolicy backoff.Policy
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -78,6 +83,9 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.BoolVar(&secureMetrics, "metrics-secure", true,
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"If set, serve validating admission webhooks for Token, ClusterToken, App and ClusterApp. "+
+			"Requires a serving certificate; see --webhook-cert-path.")
 	flag.StringVar(&webhookCertPath, "webhook-cert-path", "", "The directory that contains the webhook certificate.")
 	flag.StringVar(&webhookCertName, "webhook-cert-name", "tls.crt", "The name of the webhook certificate file.")
 	flag.StringVar(&webhookCertKey, "webhook-cert-key", "tls.key", "The name of the webhook key file.")
@@ -86,6 +94,11 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&disableHTTP2, "disable-http2", false,
 		"If set, HTTP/2 will be disabled for the metrics and webhook servers")
+	flag.DurationVar(&retryPolicy.BaseDelay, "retry-base-delay", backoff.DefaultBaseDelay,
+		"How long to wait before retrying a failed reconcile, doubled with each further failure in a row.")
+	flag.DurationVar(&retryPolicy.MaxDelay, "retry-max-delay", backoff.DefaultMaxDelay,
+		"The longest to wait between retries of a failing resource. "+
+			"Tokens and ClusterTokens are also capped at their spec.retryInterval.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -230,31 +243,37 @@ func main() {
 	registry := ghapp.NewRegistry(operatorNamespace, startupCfg)
 
 	if err := mgr.GetFieldIndexer().IndexField(ctx, &githubv1.Token{}, controller.TokenAppRefIndex, func(obj client.Object) []string {
-		t := obj.(*githubv1.Token)
-		if t.Spec.AppRef == nil {
+		ref := obj.(*githubv1.Token).GetAppRef()
+		if ref == nil {
 			return nil
 		}
-		return []string{t.Spec.AppRef.Name}
+		return []string{controller.AppRefIndexValue(ref, operatorNamespace)}
 	}); err != nil {
 		setupLog.Error(err, "unable to create field indexer", "field", controller.TokenAppRefIndex)
 		os.Exit(1)
 	}
 
 	if err := mgr.GetFieldIndexer().IndexField(ctx, &githubv1.ClusterToken{}, controller.ClusterTokenAppRefIndex, func(obj client.Object) []string {
-		ct := obj.(*githubv1.ClusterToken)
-		if ct.Spec.AppRef == nil {
+		ref := obj.(*githubv1.ClusterToken).GetAppRef()
+		if ref == nil {
 			return nil
 		}
-		ns := ct.Spec.AppRef.Namespace
-		if ns == "" {
-			ns = operatorNamespace
-		}
-		return []string{ns + "/" + ct.Spec.AppRef.Name}
+		return []string{controller.AppRefIndexValue(ref, operatorNamespace)}
 	}); err != nil {
 		setupLog.Error(err, "unable to create field indexer", "field", controller.ClusterTokenAppRefIndex)
 		os.Exit(1)
 	}
 
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &githubv1.ClusterToken{}, controller.ClusterTokenNamespaceSelectorIndex, func(obj client.Object) []string {
+		if obj.(*githubv1.ClusterToken).GetSecretNamespaceSelector() == nil {
+			return nil
+		}
+		return []string{"true"}
+	}); err != nil {
+		setupLog.Error(err, "unable to create field indexer", "field", controller.ClusterTokenNamespaceSelectorIndex)
+		os.Exit(1)
+	}
+
 	if err := mgr.GetFieldIndexer().IndexField(ctx, &githubv1.App{}, controller.AppKeyRefIndex, func(obj client.Object) []string {
 		a := obj.(*githubv1.App)
 		if a.Spec.KeyRef == nil {
@@ -266,10 +285,49 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &githubv1.ClusterApp{}, controller.ClusterAppKeyRefIndex, func(obj client.Object) []string {
+		ref := obj.(*githubv1.ClusterApp).GetKeySecretRef()
+		if ref == nil {
+			return nil
+		}
+		return []string{ref.Namespace + "/" + ref.Name}
+	}); err != nil {
+		setupLog.Error(err, "unable to create field indexer", "field", controller.ClusterAppKeyRefIndex)
+		os.Exit(1)
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &githubv1.App{}, controller.CABundleRefIndex, func(obj client.Object) []string {
+		ref := obj.(*githubv1.App).GetCABundleRef()
+		if ref == nil {
+			return nil
+		}
+		return []string{controller.CABundleRefIndexValue(ref)}
+	}); err != nil {
+		setupLog.Error(err, "unable to create field indexer", "field", controller.CABundleRefIndex)
+		os.Exit(1)
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &githubv1.ClusterApp{}, controller.CABundleRefIndex, func(obj client.Object) []string {
+		ref := obj.(*githubv1.ClusterApp).GetCABundleRef()
+		if ref == nil {
+			return nil
+		}
+		return []string{controller.CABundleRefIndexValue(ref)}
+	}); err != nil {
+		setupLog.Error(err, "unable to create field indexer", "field", controller.CABundleRefIndex)
+		os.Exit(1)
+	}
+
+	// A single recorder is shared so that its de-duplication spans every
+	// controller.
+	eventRecorder := events.NewRecorder(mgr.GetEventRecorder("github-token-manager"))
+
 	tokenBase := controller.TokenReconcilerBase{
 		Client:   mgr.GetClient(),
+		Events:   eventRecorder,
 		Metrics:  metricsRecorder,
 		Registry: registry,
+		Backoff:  retryPolicy,
 	}
 	if err = (&controller.TokenReconciler{TokenReconcilerBase: tokenBase}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Token")
@@ -281,12 +339,42 @@ func main() {
 	}
 	if err = (&controller.AppReconciler{
 		Client:   mgr.GetClient(),
+		Events:   eventRecorder,
 		Metrics:  metricsRecorder,
 		Registry: registry,
+		Backoff:  retryPolicy,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "App")
 		os.Exit(1)
 	}
+	if err = (&controller.ClusterAppReconciler{
+		Client:   mgr.GetClient(),
+		Events:   eventRecorder,
+		Metrics:  metricsRecorder,
+		Registry: registry,
+		Backoff:  retryPolicy,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterApp")
+		os.Exit(1)
+	}
+	if enableWebhooks {
+		if err = webhookv1.SetupTokenWebhookWithManager(mgr, registry); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Token")
+			os.Exit(1)
+		}
+		if err = webhookv1.SetupClusterTokenWebhookWithManager(mgr, registry); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClusterToken")
+			os.Exit(1)
+		}
+		if err = webhookv1.SetupAppWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "App")
+			os.Exit(1)
+		}
+		if err = webhookv1.SetupClusterAppWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClusterApp")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -54,3 +54,10 @@ helm.sh/chart: {{ printf "%s-%s" .Chart.Name .Chart.Version | replace "+" "_" |
 {{-     end }}
 {{-   end }}
 {{- end }}
+
+{{/*
+Name of the TLS Secret holding the webhook serving certificate
+*/}}
+{{- define "webhook.certSecretName" -}}
+{{- default (printf "%s-webhook-cert" (include "chart.fullname" .)) .Values.webhook.certSecretName }}
+{{- end }}
@@ -16,7 +16,7 @@ bases="config/crd/bases"
 out="deploy/charts/github-token-manager/templates/crds.yaml"
 
 # Order is significant only for a stable diff; keep it matching the chart.
-plurals=(clustertokens tokens apps)
+plurals=(clustertokens tokens apps clusterapps appgrants tokenpolicies clustertokenpolicies)
 
 tmp="$(mktemp "${out}.XXXXXX")"
 trap 'rm -f "$tmp"' EXIT
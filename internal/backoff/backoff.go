@@ -0,0 +1,48 @@
+package backoff
+
+import "time"
+
+const (
+	// DefaultBaseDelay is the delay before the first retry of a failed
+	// reconcile.
+	DefaultBaseDelay = 10 * time.Second
+	// DefaultMaxDelay is the longest delay between retries.
+	DefaultMaxDelay = 15 * time.Minute
+)
+
+// Policy is an exponential backoff: the delay before retrying after the nth
+// failure in a row is BaseDelay doubled n-1 times, capped at MaxDelay. A zero
+// field takes its default.
+type Policy struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// WithMaxDelay returns p also capped at maxDelay, unless maxDelay is zero.
+func (p Policy) WithMaxDelay(maxDelay time.Duration) Policy {
+	if maxDelay > 0 {
+		p.MaxDelay = min(p.maxDelay(), maxDelay)
+	}
+	return p
+}
+
+// Delay returns how long to wait before retrying after failures in a row.
+func (p Policy) Delay(failures int32) time.Duration {
+	base := p.BaseDelay
+	if base <= 0 {
+		base = DefaultBaseDelay
+	}
+	maxDelay := p.maxDelay()
+	delay := base
+	for i := int32(1); i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+func (p Policy) maxDelay() time.Duration {
+	if p.MaxDelay <= 0 {
+		return DefaultMaxDelay
+	}
+	return p.MaxDelay
+}
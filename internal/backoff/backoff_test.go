@@ -0,0 +1,32 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicy_Delay(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		failures int32
+		want     time.Duration
+	}{
+		{name: "defaults", failures: 1, want: DefaultBaseDelay},
+		{name: "no failures", policy: Policy{BaseDelay: time.Second}, failures: 0, want: time.Second},
+		{name: "first failure", policy: Policy{BaseDelay: time.Second, MaxDelay: time.Minute}, failures: 1, want: time.Second},
+		{name: "doubles", policy: Policy{BaseDelay: time.Second, MaxDelay: time.Minute}, failures: 4, want: 8 * time.Second},
+		{name: "capped", policy: Policy{BaseDelay: time.Second, MaxDelay: time.Minute}, failures: 7, want: time.Minute},
+		{name: "many failures", policy: Policy{BaseDelay: time.Second, MaxDelay: time.Minute}, failures: 1 << 30, want: time.Minute},
+		{name: "capped by override", policy: Policy{BaseDelay: time.Second}.WithMaxDelay(5 * time.Second), failures: 4, want: 5 * time.Second},
+		{name: "longer override ignored", policy: Policy{BaseDelay: time.Second, MaxDelay: time.Minute}.WithMaxDelay(time.Hour), failures: 7, want: time.Minute},
+		{name: "zero override ignored", policy: Policy{BaseDelay: time.Second, MaxDelay: time.Minute}.WithMaxDelay(0), failures: 7, want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Delay(tt.failures); got != tt.want {
+				t.Errorf("Delay(%d) = %v, want %v", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
@@ -17,10 +17,17 @@ limitations under the License.
 package controller
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,41 +42,70 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/backoff"
+	"github.com/isometry/github-token-manager/internal/events"
 	"github.com/isometry/github-token-manager/internal/ghapp"
 	"github.com/isometry/github-token-manager/internal/metrics"
 )
 
-// appRetryInterval controls how often we requeue after a failed client build.
-const appRetryInterval = time.Minute
+// installationsRefreshInterval controls how often a Ready App's
+// status.installations is refreshed from GitHub.
+const installationsRefreshInterval = 15 * time.Minute
 
 // AppReconciler reconciles an App resource by (re)building a cached ghait
 // client in the shared [ghapp.Registry] and surfacing its readiness via
 // status conditions.
 type AppReconciler struct {
 	client.Client
+	Events   *events.Recorder
 	Metrics  *metrics.Recorder
 	Registry *ghapp.Registry
+	// Backoff is the policy failed client builds are retried by.
+	Backoff backoff.Policy
 }
 
 // +kubebuilder:rbac:groups=github.as-code.io,resources=apps,verbs=get;list;watch
 // +kubebuilder:rbac:groups=github.as-code.io,resources=apps/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 
 func (r *AppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	logger := log.FromContext(ctx)
-
 	key := ghapp.Key{Namespace: req.Namespace, Name: req.Name}
+	return reconcileAppLike[githubv1.App](ctx, r.Client, r.Metrics, r.Events, r.Backoff, r.Registry, req, key, ControllerNameApp)
+}
+
+// reconcileAppLike runs the reconcile body shared by App and ClusterApp:
+// (re)build the cached ghait client under key and surface its readiness via
+// status conditions. Failed builds are counted in status and retried once
+// the App has backed off for its failures in a row, as policy dictates.
+func reconcileAppLike[T any, PT interface {
+	appObject
+	*T
+}](
+	ctx context.Context,
+	c client.Client,
+	recorder *metrics.Recorder,
+	eventRecorder *events.Recorder,
+	policy backoff.Policy,
+	registry *ghapp.Registry,
+	req ctrl.Request,
+	key ghapp.Key,
+	controllerName string,
+) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
 
-	app := &githubv1.App{}
-	if err := r.Get(ctx, req.NamespacedName, app); err != nil {
+	app := PT(new(T))
+	if err := c.Get(ctx, req.NamespacedName, app); err != nil {
 		if apierrors.IsNotFound(err) {
-			r.Registry.Invalidate(key)
+			registry.Invalidate(key)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
 	}
 
-	cfg, version, reason, resolveErr := resolveAppConfig(ctx, r.Client, app)
+	original := app.GetAppStatus().DeepCopy()
+	cfg, version, reason, resolveErr := resolveAppConfig(ctx, c, app)
 	var (
 		buildErr error
 		failure  string
@@ -77,25 +113,33 @@ func (r *AppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	if resolveErr != nil {
 		buildErr = resolveErr
 		failure = reason
-	} else if _, err := r.Registry.ForApp(ctx, key, version, cfg); err != nil {
+	} else if err := resolveInstallation(ctx, registry, key, version, cfg, app); err != nil {
+		buildErr = err
+		failure = githubv1.ReasonSetupFailed
+		if errors.Is(err, ghapp.ErrInstallationNotFound) {
+			failure = githubv1.ReasonInstallationNotFound
+		}
+	} else if _, err := registry.ForApp(ctx, key, installationVersion(version, cfg, app), cfg); err != nil {
 		buildErr = err
 		failure = githubv1.ReasonSetupFailed
 	}
 
 	if buildErr != nil {
-		logger.Error(buildErr, "failed to build GitHub App client", "app", req.NamespacedName)
-		if r.Metrics != nil {
-			r.Metrics.RecordConfigError(ctx, ControllerNameApp, "app")
+		logger.Error(buildErr, "failed to build GitHub App client", strings.ToLower(app.GetType()), req.NamespacedName)
+		if recorder != nil {
+			recorder.RecordConfigError(ctx, controllerName, "app")
 		}
-		r.Registry.Invalidate(key)
+		registry.Invalidate(key)
 		ready := metav1.Condition{
 			Type:    githubv1.ConditionTypeReady,
 			Status:  metav1.ConditionFalse,
 			Reason:  failure,
 			Message: buildErr.Error(),
 		}
+		eventReason := events.ReasonAppNotReady
 		var keyValid *metav1.Condition
-		if app.Spec.ValidateKey {
+		if app.GetValidateKey() {
+			eventReason = events.ReasonKeyValidationFailed
 			keyValid = &metav1.Condition{
 				Type:    githubv1.ConditionTypeKeyValid,
 				Status:  metav1.ConditionFalse,
@@ -103,10 +147,12 @@ func (r *AppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 				Message: buildErr.Error(),
 			}
 		}
-		if err := r.writeAppStatus(ctx, app, ready, keyValid); err != nil {
+		eventRecorder.Warning(app, eventReason, events.ActionBuildClient, failure+": "+buildErr.Error())
+		failures := app.GetAppStatus().RecordFailure(time.Now())
+		if err := writeAppStatus(ctx, c, app, original, ready, keyValid); err != nil {
 			return ctrl.Result{}, err
 		}
-		return ctrl.Result{RequeueAfter: appRetryInterval}, nil
+		return ctrl.Result{RequeueAfter: policy.Delay(failures)}, nil
 	}
 
 	ready := metav1.Condition{
@@ -116,7 +162,7 @@ func (r *AppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		Message: "GitHub App client ready",
 	}
 	var keyValid *metav1.Condition
-	if app.Spec.ValidateKey {
+	if app.GetValidateKey() {
 		keyValid = &metav1.Condition{
 			Type:    githubv1.ConditionTypeKeyValid,
 			Status:  metav1.ConditionTrue,
@@ -124,32 +170,119 @@ func (r *AppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 			Message: "signer key validated",
 		}
 	}
-	if err := r.writeAppStatus(ctx, app, ready, keyValid); err != nil {
+	requeueAfter := installationsRefreshInterval
+	if refreshInstallations(ctx, registry, key, app) {
+		// The selector resolved to an installation that is no longer listed,
+		// e.g. because the App was reinstalled: resolve it afresh.
+		stale := app.GetAppStatus().Installation
+		logger.Info("resolved installation no longer listed", "installation", stale.Selector, "installationID", stale.ID)
+		registry.ForgetInstallation(key, stale.ID)
+		app.GetAppStatus().Installation = nil
+		requeueAfter = policy.Delay(1)
+	}
+	app.GetAppStatus().ResetFailures()
+	if err := writeAppStatus(ctx, c, app, original, ready, keyValid); err != nil {
 		return ctrl.Result{}, err
 	}
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// refreshInstallations records the App's installations in status, and the
+// outcome in its InstallationsListed condition. Failure leaves the previous
+// list in place: it is informational, and does not affect whether the App
+// can mint tokens. It reports whether the installation recorded in
+// status.installation is missing from a successful listing.
+func refreshInstallations(ctx context.Context, registry *ghapp.Registry, key ghapp.Key, app appObject) (stale bool) {
+	installations, err := registry.Installations(ctx, key)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to list installations")
+		app.SetStatusCondition(metav1.Condition{
+			Type:    githubv1.ConditionTypeInstallationsListed,
+			Status:  metav1.ConditionFalse,
+			Reason:  githubv1.ReasonListFailed,
+			Message: err.Error(),
+		})
+		return false
+	}
+
+	status := app.GetAppStatus()
+	status.Installations = make([]githubv1.InstallationStatus, 0, len(installations))
+	for _, installation := range installations {
+		status.Installations = append(status.Installations, githubv1.InstallationStatus{
+			ID:                  installation.GetID(),
+			Account:             installation.GetAccount().GetLogin(),
+			RepositorySelection: installation.GetRepositorySelection(),
+			Permissions:         ghapp.PermissionMap(installation.GetPermissions()),
+			Suspended:           installation.SuspendedAt != nil,
+		})
+	}
+	slices.SortFunc(status.Installations, func(a, b githubv1.InstallationStatus) int {
+		return cmp.Or(strings.Compare(a.Account, b.Account), cmp.Compare(a.ID, b.ID))
+	})
+	status.InstallationCount = int32(len(status.Installations))
+	app.SetStatusCondition(metav1.Condition{
+		Type:    githubv1.ConditionTypeInstallationsListed,
+		Status:  metav1.ConditionTrue,
+		Reason:  githubv1.ReasonReconciled,
+		Message: fmt.Sprintf("Listed %d installations", len(status.Installations)),
+	})
+	return status.Installation != nil && !slices.ContainsFunc(status.Installations, func(listed githubv1.InstallationStatus) bool {
+		return listed.ID == status.Installation.ID
+	})
+}
+
+// resolveInstallation sets cfg.InstallationID from the App's installation
+// selector, if any, and records it in status.installation. A matching
+// status.installation is reused; otherwise the selector is looked up with a
+// client built for the App without a default installation.
+func resolveInstallation(ctx context.Context, registry *ghapp.Registry, key ghapp.Key, version string, cfg *ghapp.OperatorConfig, app appObject) error {
+	status := app.GetAppStatus()
+	selector := app.GetInstallationSelector()
+	if selector == nil {
+		status.Installation = nil
+		return nil
+	}
+
+	if resolved := status.Installation; resolved == nil || resolved.Selector != selector.String() || resolved.ID == 0 {
+		if _, err := registry.ForApp(ctx, key, version, cfg); err != nil {
+			return err
+		}
+		id, err := registry.Installation(ctx, key, selector.String())
+		if err != nil {
+			return err
+		}
+		status.Installation = &githubv1.ResolvedInstallation{Selector: selector.String(), ID: id}
+	}
+	cfg.InstallationID = status.Installation.ID
+	return nil
+}
+
+// installationVersion extends a cached-client version with the installation
+// an App's selector resolved to, so that the client is rebuilt once the
+// default installation is known or changes.
+func installationVersion(version string, cfg *ghapp.OperatorConfig, app appObject) string {
+	if app.GetInstallationSelector() == nil {
+		return version
+	}
+	return version + "/" + strconv.FormatInt(cfg.InstallationID, 10)
 }
 
 // writeAppStatus applies the Ready condition, applies or clears the KeyValid
 // condition (nil clears), bumps ObservedGeneration, and writes status only if
-// anything actually changed.
-func (r *AppReconciler) writeAppStatus(ctx context.Context, app *githubv1.App, ready metav1.Condition, keyValid *metav1.Condition) error {
-	changed := app.SetStatusCondition(ready)
+// it differs from original.
+func writeAppStatus(ctx context.Context, c client.Client, app appObject, original *githubv1.AppStatus, ready metav1.Condition, keyValid *metav1.Condition) error {
+	status := app.GetAppStatus()
+	app.SetStatusCondition(ready)
 	if keyValid != nil {
-		if app.SetStatusCondition(*keyValid) {
-			changed = true
-		}
-	} else if meta.RemoveStatusCondition(&app.Status.Conditions, githubv1.ConditionTypeKeyValid) {
-		changed = true
+		app.SetStatusCondition(*keyValid)
+	} else {
+		meta.RemoveStatusCondition(&status.Conditions, githubv1.ConditionTypeKeyValid)
 	}
-	if app.Status.ObservedGeneration != app.Generation {
-		app.Status.ObservedGeneration = app.Generation
-		changed = true
-	}
-	if !changed {
+	status.ObservedGeneration = app.GetGeneration()
+	if equality.Semantic.DeepEqual(original, status) {
 		return nil
 	}
-	return r.Status().Update(ctx, app)
+	return c.Status().Update(ctx, app)
 }
 
 // mapSecretToApps enqueues every App in the Secret's namespace whose
@@ -200,6 +333,53 @@ func (r *AppReconciler) secretReferencedByApp(obj client.Object) bool {
 	return len(apps.Items) > 0
 }
 
+// mapCABundleToApps enqueues every App in the object's namespace whose
+// spec.caBundleRef names the ConfigMap or Secret, so that CA rotation
+// rebuilds the App's clients.
+func (r *AppReconciler) mapCABundleToApps(ctx context.Context, obj client.Object) []reconcile.Request {
+	var apps githubv1.AppList
+	if err := r.List(ctx, &apps,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{CABundleRefIndex: caBundleObjectIndexValue(obj)},
+	); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list Apps for CA bundle", "object", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+	out := make([]reconcile.Request, 0, len(apps.Items))
+	for i := range apps.Items {
+		out = append(out, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&apps.Items[i])})
+	}
+	return out
+}
+
+// caBundleReferencedByApp reports whether at least one App references the
+// ConfigMap or Secret via spec.caBundleRef; see secretReferencedByApp.
+func (r *AppReconciler) caBundleReferencedByApp(obj client.Object) bool {
+	var apps githubv1.AppList
+	if err := r.List(context.Background(), &apps,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{CABundleRefIndex: caBundleObjectIndexValue(obj)},
+		client.Limit(1),
+	); err != nil {
+		return true
+	}
+	return len(apps.Items) > 0
+}
+
+// caBundleObjectIndexValue returns the [CABundleRefIndex] value under which
+// Apps referencing the given ConfigMap or Secret are indexed.
+func caBundleObjectIndexValue(obj client.Object) string {
+	ref := &githubv1.CABundleReference{
+		Kind:      githubv1.CABundleKindConfigMap,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+	if _, ok := obj.(*corev1.Secret); ok {
+		ref.Kind = githubv1.CABundleKindSecret
+	}
+	return CABundleRefIndexValue(ref)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *AppReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
@@ -212,6 +392,20 @@ func (r *AppReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				predicate.NewPredicateFuncs(r.secretReferencedByApp),
 			),
 		).
+		Watches(&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.mapCABundleToApps),
+			builder.WithPredicates(
+				predicate.ResourceVersionChangedPredicate{},
+				predicate.NewPredicateFuncs(r.caBundleReferencedByApp),
+			),
+		).
+		Watches(&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.mapCABundleToApps),
+			builder.WithPredicates(
+				predicate.ResourceVersionChangedPredicate{},
+				predicate.NewPredicateFuncs(r.caBundleReferencedByApp),
+			),
+		).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 3}).
 		Complete(r)
 }
@@ -17,12 +17,14 @@ limitations under the License.
 package controller
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -30,21 +32,57 @@ import (
 	"github.com/isometry/github-token-manager/internal/ghapp"
 )
 
-// AppKeyRefIndex is the field-indexer key used to watch Secrets and map them
-// back to the Apps that reference them via spec.keyRef.name.
-const AppKeyRefIndex = ".spec.keyRef.name"
+// Field-indexer keys used to watch Secrets and map them back to the Apps and
+// ClusterApps that reference them via spec.keyRef.
+const (
+	AppKeyRefIndex        = ".spec.keyRef.name"
+	ClusterAppKeyRefIndex = ".spec.keyRef"
+)
+
+// CABundleRefIndex is the field-indexer key, shared by App and ClusterApp,
+// used to map ConfigMaps and Secrets back to the Apps whose spec.caBundleRef
+// names them. Values are built by [CABundleRefIndexValue].
+const CABundleRefIndex = ".spec.caBundleRef"
+
+// defaultCABundleDataKey matches the kubebuilder default on
+// CABundleReference.Key.
+const defaultCABundleDataKey = "ca.crt"
 
 // defaultKeyRefDataKey matches the kubebuilder default on
 // AppSpec.KeyRef.Key; mirrored here so the in-process Get path agrees with
 // any object that bypassed defaulting (e.g. tests using the typed client).
 const defaultKeyRefDataKey = "private-key.pem"
 
-// resolveAppConfig returns an [*ghapp.OperatorConfig] for the given App
-// together with a version string capturing every input that affects client
-// identity.
+// appObject is implemented by both App and ClusterApp.
+type appObject interface {
+	client.Object
+
+	GetType() string
+	GetAppID() int64
+	GetInstallationID() int64
+	GetInstallationSelector() *githubv1.InstallationSelector
+	GetProvider() string
+	GetKey() string
+	GetValidateKey() bool
+	GetBaseURL() string
+	GetUploadURL() string
+	GetCABundle() string
+	GetProxyURL() string
+	GetCABundleRef() *githubv1.CABundleReference
+	GetKeySecretRef() *githubv1.ClusterKeySecretReference
+	GetAppStatus() *githubv1.AppStatus
+	SetStatusCondition(condition metav1.Condition) (changed bool)
+}
+
+// resolveAppConfig returns an [*ghapp.OperatorConfig] for the given App or
+// ClusterApp together with a version string capturing every input that
+// affects client identity.
 //
 // Cloud-KMS configs pass through unchanged with the version set to the App's
-// spec generation. provider:"secret" configs translate to ghait's file
+// spec generation, suffixed with the GitHub Enterprise Server endpoint,
+// proxy and a digest of the CA bundle when any is set. A caBundleRef is read
+// into CABundle, so the digest also changes when the referenced ConfigMap or
+// Secret does. provider:"secret" configs translate to ghait's file
 // provider with the literal PEM bytes in Key (its os.Stat fallback handles
 // literal PEM bytes), and the version composes the spec generation with the
 // referenced Secret's ResourceVersion so cached clients invalidate on key
@@ -52,26 +90,40 @@ const defaultKeyRefDataKey = "private-key.pem"
 //
 // reason is one of the v1.Reason* constants when err is non-nil, suitable
 // for the caller to write into a status condition.
-func resolveAppConfig(ctx context.Context, c client.Reader, app *githubv1.App) (cfg *ghapp.OperatorConfig, version, reason string, err error) {
+func resolveAppConfig(ctx context.Context, c client.Reader, app appObject) (cfg *ghapp.OperatorConfig, version, reason string, err error) {
 	cfg = &ghapp.OperatorConfig{
-		AppID:          app.Spec.AppID,
-		InstallationID: app.Spec.InstallationID,
-		Provider:       app.Spec.Provider,
-		Key:            app.Spec.Key,
-		ValidateKey:    app.Spec.ValidateKey,
+		AppID:          app.GetAppID(),
+		InstallationID: app.GetInstallationID(),
+		Provider:       app.GetProvider(),
+		Key:            app.GetKey(),
+		ValidateKey:    app.GetValidateKey(),
+		BaseURL:        app.GetBaseURL(),
+		UploadURL:      app.GetUploadURL(),
+		CABundle:       app.GetCABundle(),
+		ProxyURL:       app.GetProxyURL(),
 	}
 
-	if app.Spec.Provider != "secret" {
-		return cfg, strconv.FormatInt(app.Generation, 10), "", nil
+	if ref := app.GetCABundleRef(); ref != nil {
+		caBundle, err := resolveCABundle(ctx, c, ref)
+		if err != nil {
+			return nil, "", githubv1.ReasonCABundleInvalid, err
+		}
+		cfg.CABundle = caBundle
+	}
+	endpoint := ghapp.EndpointVersion(cfg)
+
+	keyRef := app.GetKeySecretRef()
+	if app.GetProvider() != "secret" || keyRef == nil {
+		return cfg, strconv.FormatInt(app.GetGeneration(), 10) + endpoint, "", nil
 	}
 
-	dataKey := app.Spec.KeyRef.Key
+	dataKey := keyRef.Key
 	if dataKey == "" {
 		dataKey = defaultKeyRefDataKey
 	}
 
 	var secret corev1.Secret
-	nn := types.NamespacedName{Namespace: app.Namespace, Name: app.Spec.KeyRef.Name}
+	nn := types.NamespacedName{Namespace: keyRef.Namespace, Name: keyRef.Name}
 	if err := c.Get(ctx, nn, &secret); err != nil {
 		if apierrors.IsNotFound(err) {
 			return nil, "", githubv1.ReasonSecretNotFound, fmt.Errorf("Secret %s not found: %w", nn, err)
@@ -86,5 +138,44 @@ func resolveAppConfig(ctx context.Context, c client.Reader, app *githubv1.App) (
 
 	cfg.Provider = "file"
 	cfg.Key = string(pemBytes)
-	return cfg, fmt.Sprintf("%d:%s", app.Generation, secret.ResourceVersion), "", nil
+	return cfg, fmt.Sprintf("%d:%s%s", app.GetGeneration(), secret.ResourceVersion, endpoint), "", nil
+}
+
+// CABundleRefIndexValue returns the field-index value identifying the
+// ConfigMap or Secret a namespace-qualified caBundleRef names.
+func CABundleRefIndexValue(ref *githubv1.CABundleReference) string {
+	kind := ref.Kind
+	if kind == "" {
+		kind = githubv1.CABundleKindConfigMap
+	}
+	return kind + "/" + ref.Namespace + "/" + ref.Name
+}
+
+// resolveCABundle reads the PEM CA bundle from the ConfigMap or Secret named
+// by a namespace-qualified caBundleRef.
+func resolveCABundle(ctx context.Context, c client.Reader, ref *githubv1.CABundleReference) (string, error) {
+	dataKey := ref.Key
+	if dataKey == "" {
+		dataKey = defaultCABundleDataKey
+	}
+	nn := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+
+	var caBundle string
+	if ref.Kind == githubv1.CABundleKindSecret {
+		var secret corev1.Secret
+		if err := c.Get(ctx, nn, &secret); err != nil {
+			return "", fmt.Errorf("fetch CA bundle Secret %s: %w", nn, err)
+		}
+		caBundle = string(secret.Data[dataKey])
+	} else {
+		var configMap corev1.ConfigMap
+		if err := c.Get(ctx, nn, &configMap); err != nil {
+			return "", fmt.Errorf("fetch CA bundle ConfigMap %s: %w", nn, err)
+		}
+		caBundle = configMap.Data[dataKey]
+	}
+	if caBundle == "" {
+		return "", fmt.Errorf("%s %s has no data under key %q", cmp.Or(ref.Kind, githubv1.CABundleKindConfigMap), nn, dataKey)
+	}
+	return caBundle, nil
 }
@@ -19,8 +19,8 @@ package controller
 import (
 	"context"
 	"fmt"
-	"time"
 
+	"github.com/google/go-github/v84/github"
 	"github.com/isometry/ghait/v84"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -30,19 +30,16 @@ import (
 
 	githubv1 "github.com/isometry/github-token-manager/api/v1"
 	"github.com/isometry/github-token-manager/internal/ghapp"
+	"github.com/isometry/github-token-manager/internal/policy"
 )
 
-// Field-indexer keys used to watch App changes and map them back to the
-// Tokens/ClusterTokens that reference them.
+// Field-indexer keys used to watch App and ClusterApp changes and map them
+// back to the Tokens/ClusterTokens that reference them.
 const (
-	TokenAppRefIndex        = ".spec.appRef.name"
+	TokenAppRefIndex        = ".spec.appRef"
 	ClusterTokenAppRefIndex = ".spec.appRef"
 )
 
-// appRefRetryInterval is how long a Token/ClusterToken waits before retrying
-// when its App reference is unavailable.
-const appRefRetryInterval = 30 * time.Second
-
 // appResolution describes the outcome of looking up the ghait client for a
 // Token/ClusterToken's spec.appRef (or its absence, which falls back to the
 // startup configuration). Exactly one of Client or FailCondition is populated.
@@ -50,18 +47,20 @@ type appResolution struct {
 	// Client, if non-nil, is the ghait client to use for minting tokens.
 	Client ghait.GHAIT
 
+	// Key is the registry key Client is cached under.
+	Key ghapp.Key
+
+	// GitHub is an unauthenticated go-github client for the resolved App's
+	// endpoint, CA bundle and proxy; it is always set.
+	GitHub *github.Client
+
 	// FailCondition, if non-nil, should be written to the owner's status and
-	// surfaced to the user. The caller should also requeue after
-	// RequeueAfter.
+	// surfaced to the user, and the owner retried once it has backed off.
 	FailCondition *metav1.Condition
-
-	// RequeueAfter is the duration after which the Token/ClusterToken should
-	// be re-reconciled when resolution failed. Zero when Client is set.
-	RequeueAfter time.Duration
 }
 
 // failResolution builds an appResolution carrying a not-Ready condition with
-// the given reason/message and the standard retry interval.
+// the given reason/message.
 func failResolution(reason, message string) appResolution {
 	return appResolution{
 		FailCondition: &metav1.Condition{
@@ -70,7 +69,7 @@ func failResolution(reason, message string) appResolution {
 			Reason:  reason,
 			Message: message,
 		},
-		RequeueAfter: appRefRetryInterval,
+		GitHub: github.NewClient(nil),
 	}
 }
 
@@ -81,14 +80,19 @@ func failResolution(reason, message string) appResolution {
 // situation changes.
 //
 // For ClusterToken callers, an empty ref.Namespace is resolved against the
-// operator's own namespace.
-func resolveApp(ctx context.Context, c client.Client, reg *ghapp.Registry, ref *githubv1.AppReference) appResolution {
+// operator's own namespace. For Token callers, from is the Token's namespace
+// and a ref into any other namespace must be permitted by an AppGrant there.
+func resolveApp(ctx context.Context, c client.Client, reg *ghapp.Registry, ref *githubv1.AppReference, from string) appResolution {
 	if ref == nil {
 		cli, err := reg.Startup(ctx)
 		if err != nil {
 			return failResolution(githubv1.ReasonNoStartupConfig, err.Error())
 		}
-		return appResolution{Client: cli}
+		return appResolution{Client: cli, Key: ghapp.StartupKey, GitHub: reg.GitHubClient(ghapp.StartupKey)}
+	}
+
+	if ref.IsClusterApp() {
+		return resolveClusterApp(ctx, c, reg, ref.Name)
 	}
 
 	namespace := ref.Namespace
@@ -97,6 +101,19 @@ func resolveApp(ctx context.Context, c client.Client, reg *ghapp.Registry, ref *
 	}
 	nn := types.NamespacedName{Namespace: namespace, Name: ref.Name}
 
+	if from != "" && from != namespace {
+		// Checked before the App is fetched, so that an ungranted namespace
+		// learns nothing about the Apps in another.
+		granted, err := policy.AppGranted(ctx, c, nn, from)
+		if err != nil {
+			return failResolution(githubv1.ReasonSetupFailed, fmt.Sprintf("check AppGrants for App %s: %v", nn, err))
+		}
+		if !granted {
+			return failResolution(githubv1.ReasonGrantMissing,
+				fmt.Sprintf("no AppGrant in namespace %s permits namespace %s to reference App %s", namespace, from, ref.Name))
+		}
+	}
+
 	var app githubv1.App
 	if err := c.Get(ctx, nn, &app); err != nil {
 		if apierrors.IsNotFound(err) {
@@ -114,5 +131,43 @@ func resolveApp(ctx context.Context, c client.Client, reg *ghapp.Registry, ref *
 	if !ok {
 		return failResolution(githubv1.ReasonAppNotReady, fmt.Sprintf("App %s client not yet cached", nn))
 	}
-	return appResolution{Client: cli}
+	return appResolution{Client: cli, Key: key, GitHub: reg.GitHubClient(key)}
+}
+
+// resolveClusterApp returns the ghait client for the named ClusterApp, or a
+// condition describing why it is unavailable.
+func resolveClusterApp(ctx context.Context, c client.Client, reg *ghapp.Registry, name string) appResolution {
+	var app githubv1.ClusterApp
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, &app); err != nil {
+		if apierrors.IsNotFound(err) {
+			return failResolution(githubv1.ReasonAppNotFound, fmt.Sprintf("ClusterApp %s not found", name))
+		}
+		return failResolution(githubv1.ReasonSetupFailed, fmt.Sprintf("fetch ClusterApp %s: %v", name, err))
+	}
+
+	if !meta.IsStatusConditionTrue(app.Status.Conditions, githubv1.ConditionTypeReady) {
+		return failResolution(githubv1.ReasonAppNotReady, fmt.Sprintf("ClusterApp %s is not Ready", name))
+	}
+
+	key := ghapp.Key{Name: name}
+	cli, ok := reg.Lookup(key)
+	if !ok {
+		return failResolution(githubv1.ReasonAppNotReady, fmt.Sprintf("ClusterApp %s client not yet cached", name))
+	}
+	return appResolution{Client: cli, Key: key, GitHub: reg.GitHubClient(key)}
+}
+
+// AppRefIndexValue returns the field-index value identifying the App or
+// ClusterApp that ref resolves to: "namespace/name" for an App, with an empty
+// namespace defaulted to operatorNamespace, and the bare name for a
+// ClusterApp, whose names cannot contain a slash.
+func AppRefIndexValue(ref *githubv1.AppReference, operatorNamespace string) string {
+	if ref.IsClusterApp() {
+		return ref.Name
+	}
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = operatorNamespace
+	}
+	return namespace + "/" + ref.Name
 }
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/backoff"
+	"github.com/isometry/github-token-manager/internal/events"
+	"github.com/isometry/github-token-manager/internal/ghapp"
+	"github.com/isometry/github-token-manager/internal/metrics"
+)
+
+// ClusterAppReconciler reconciles a ClusterApp resource into the same shared
+// [ghapp.Registry] as App, keyed by name alone.
+type ClusterAppReconciler struct {
+	client.Client
+	Events   *events.Recorder
+	Metrics  *metrics.Recorder
+	Registry *ghapp.Registry
+	// Backoff is the policy failed client builds are retried by.
+	Backoff backoff.Policy
+}
+
+// +kubebuilder:rbac:groups=github.as-code.io,resources=clusterapps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=github.as-code.io,resources=clusterapps/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+
+func (r *ClusterAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	key := ghapp.Key{Name: req.Name}
+	return reconcileAppLike[githubv1.ClusterApp](ctx, r.Client, r.Metrics, r.Events, r.Backoff, r.Registry, req, key, ControllerNameClusterApp)
+}
+
+// mapSecretToClusterApps enqueues every ClusterApp whose spec.keyRef names
+// the Secret.
+func (r *ClusterAppReconciler) mapSecretToClusterApps(ctx context.Context, obj client.Object) []reconcile.Request {
+	var apps githubv1.ClusterAppList
+	if err := r.List(ctx, &apps,
+		client.MatchingFields{ClusterAppKeyRefIndex: obj.GetNamespace() + "/" + obj.GetName()},
+	); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ClusterApps for Secret", "secret", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+	out := make([]reconcile.Request, 0, len(apps.Items))
+	for i := range apps.Items {
+		out = append(out, reconcile.Request{NamespacedName: types.NamespacedName{Name: apps.Items[i].Name}})
+	}
+	return out
+}
+
+// secretReferencedByClusterApp reports whether at least one ClusterApp
+// references the Secret via spec.keyRef; see secretReferencedByApp.
+func (r *ClusterAppReconciler) secretReferencedByClusterApp(obj client.Object) bool {
+	var apps githubv1.ClusterAppList
+	if err := r.List(context.Background(), &apps,
+		client.MatchingFields{ClusterAppKeyRefIndex: obj.GetNamespace() + "/" + obj.GetName()},
+		client.Limit(1),
+	); err != nil {
+		return true
+	}
+	return len(apps.Items) > 0
+}
+
+// mapCABundleToClusterApps enqueues every ClusterApp whose spec.caBundleRef
+// names the ConfigMap or Secret.
+func (r *ClusterAppReconciler) mapCABundleToClusterApps(ctx context.Context, obj client.Object) []reconcile.Request {
+	var apps githubv1.ClusterAppList
+	if err := r.List(ctx, &apps,
+		client.MatchingFields{CABundleRefIndex: caBundleObjectIndexValue(obj)},
+	); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ClusterApps for CA bundle", "object", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+	out := make([]reconcile.Request, 0, len(apps.Items))
+	for i := range apps.Items {
+		out = append(out, reconcile.Request{NamespacedName: types.NamespacedName{Name: apps.Items[i].Name}})
+	}
+	return out
+}
+
+// caBundleReferencedByClusterApp reports whether at least one ClusterApp
+// references the ConfigMap or Secret via spec.caBundleRef; see
+// secretReferencedByApp.
+func (r *ClusterAppReconciler) caBundleReferencedByClusterApp(obj client.Object) bool {
+	var apps githubv1.ClusterAppList
+	if err := r.List(context.Background(), &apps,
+		client.MatchingFields{CABundleRefIndex: caBundleObjectIndexValue(obj)},
+		client.Limit(1),
+	); err != nil {
+		return true
+	}
+	return len(apps.Items) > 0
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterAppReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&githubv1.ClusterApp{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Named(ControllerNameClusterApp).
+		Watches(&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.mapSecretToClusterApps),
+			builder.WithPredicates(
+				predicate.ResourceVersionChangedPredicate{},
+				predicate.NewPredicateFuncs(r.secretReferencedByClusterApp),
+			),
+		).
+		Watches(&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.mapCABundleToClusterApps),
+			builder.WithPredicates(
+				predicate.ResourceVersionChangedPredicate{},
+				predicate.NewPredicateFuncs(r.caBundleReferencedByClusterApp),
+			),
+		).
+		Watches(&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.mapCABundleToClusterApps),
+			builder.WithPredicates(
+				predicate.ResourceVersionChangedPredicate{},
+				predicate.NewPredicateFuncs(r.caBundleReferencedByClusterApp),
+			),
+		).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 3}).
+		Complete(r)
+}
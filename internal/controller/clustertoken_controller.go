@@ -19,6 +19,7 @@ package controller
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -31,15 +32,24 @@ import (
 	githubv1 "github.com/isometry/github-token-manager/api/v1"
 )
 
+// ClusterTokenNamespaceSelectorIndex is the field-indexer key under which
+// ClusterTokens with a spec.secret.namespaceSelector are indexed, all with
+// the value "true", so that Namespace events only map to those.
+const ClusterTokenNamespaceSelectorIndex = ".spec.secret.namespaceSelector"
+
 // ClusterTokenReconciler reconciles a ClusterToken object.
 type ClusterTokenReconciler struct {
 	TokenReconcilerBase
 }
 
-// +kubebuilder:rbac:groups=github.as-code.io,resources=clustertokens,verbs=get;list;watch
+// +kubebuilder:rbac:groups=github.as-code.io,resources=clustertokens,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=github.as-code.io,resources=clustertokens/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=github.as-code.io,resources=clustertokens/finalizers,verbs=update
 // +kubebuilder:rbac:groups=github.as-code.io,resources=apps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=github.as-code.io,resources=clusterapps,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -72,15 +82,55 @@ func (r *ClusterTokenReconciler) mapAppToClusterTokens(ctx context.Context, obj
 	return requests
 }
 
+// mapClusterAppToClusterTokens enqueues every ClusterToken whose
+// spec.appRef names this ClusterApp.
+func (r *ClusterTokenReconciler) mapClusterAppToClusterTokens(ctx context.Context, obj client.Object) []reconcile.Request {
+	var list githubv1.ClusterTokenList
+	if err := r.List(ctx, &list, client.MatchingFields{ClusterTokenAppRefIndex: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ClusterTokens for ClusterApp", "clusterapp", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for i := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+	}
+	return requests
+}
+
+// mapNamespaceToClusterTokens enqueues every ClusterToken with a
+// spec.secret.namespaceSelector, found through its field index, so that
+// label changes on a Namespace add or remove its copy of the Secret.
+func (r *ClusterTokenReconciler) mapNamespaceToClusterTokens(ctx context.Context, obj client.Object) []reconcile.Request {
+	var list githubv1.ClusterTokenList
+	if err := r.List(ctx, &list, client.MatchingFields{ClusterTokenNamespaceSelectorIndex: "true"}); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ClusterTokens for Namespace", "namespace", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for i := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ClusterTokenReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&githubv1.ClusterToken{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Named(ControllerNameClusterToken).
+		Owns(&corev1.Secret{}).
 		Watches(&githubv1.App{},
 			handler.EnqueueRequestsFromMapFunc(r.mapAppToClusterTokens),
 			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
 		).
+		Watches(&githubv1.ClusterApp{},
+			handler.EnqueueRequestsFromMapFunc(r.mapClusterAppToClusterTokens),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToClusterTokens),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 5}).
 		Complete(r)
 }
@@ -20,4 +20,5 @@ const (
 	ControllerNameToken        = "github-token"
 	ControllerNameClusterToken = "github-clustertoken"
 	ControllerNameApp          = "github-app"
+	ControllerNameClusterApp   = "github-clusterapp"
 )
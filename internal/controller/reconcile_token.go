@@ -18,13 +18,24 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/backoff"
+	"github.com/isometry/github-token-manager/internal/events"
 	"github.com/isometry/github-token-manager/internal/ghapp"
 	"github.com/isometry/github-token-manager/internal/metrics"
+	"github.com/isometry/github-token-manager/internal/policy"
 	tm "github.com/isometry/github-token-manager/internal/tokenmanager"
 )
 
@@ -33,10 +44,30 @@ import (
 // reconcile helper can take a single receiver value.
 type TokenReconcilerBase struct {
 	client.Client
+	Events   *events.Recorder
 	Metrics  *metrics.Recorder
 	Registry *ghapp.Registry
+	// Backoff is the policy failed reconciles are retried by, also capped
+	// at each owner's spec.retryInterval.
+	Backoff backoff.Policy
 }
 
+// failed surfaces condition on owner and counts a failed reconcile in its
+// status, requeueing it once it has backed off for its failures in a row,
+// but no sooner than floor.
+func (r *TokenReconcilerBase) failed(ctx context.Context, owner tm.TokenManager, condition metav1.Condition, floor time.Duration) (ctrl.Result, error) {
+	owner.SetStatusCondition(condition)
+	owner.GetStatusFailures().RecordFailure(time.Now())
+	if err := r.Status().Update(ctx, owner); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: max(tm.RetryAfter(r.Backoff, owner), floor)}, nil
+}
+
+// repositorySelectorResyncInterval is how often a Token/ClusterToken with a
+// repository selector re-resolves it.
+const repositorySelectorResyncInterval = 15 * time.Minute
+
 // reconcileTokenLike runs the post-Get reconcile body shared by Token and
 // ClusterToken: fetch the typed object, resolve its App reference, surface
 // any failure as a status condition, then hand off to tokenmanager to
@@ -58,27 +89,148 @@ func reconcileTokenLike[T any, PT interface {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	resolution := resolveApp(ctx, r.Client, r.Registry, owner.GetAppRef())
+	if !owner.GetDeletionTimestamp().IsZero() {
+		// Revocation authenticates as the token itself, so the App is only
+		// consulted for how to reach GitHub; if it is already gone,
+		// github.com is assumed and a failed revocation leaves the token to
+		// expire.
+		resolution := resolveApp(ctx, r.Client, r.Registry, owner.GetAppRef(), owner.GetNamespace())
+		tokenSecret := tm.NewTokenSecret(req.NamespacedName, owner, controllerName,
+			tm.WithClient(r.Client),
+			tm.WithLogger(logger),
+			tm.WithMetrics(r.Metrics),
+			tm.WithEvents(r.Events),
+			tm.WithGitHubClient(resolution.GitHub),
+		)
+		return ctrl.Result{}, tokenSecret.Finalize(ctx)
+	}
+
+	violations, err := policy.Evaluate(ctx, r.Client, owner)
+	if err != nil {
+		logger.Error(err, "failed to evaluate token policies")
+		result, err := r.failed(ctx, owner, metav1.Condition{
+			Type:    githubv1.ConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  githubv1.ReasonSetupFailed,
+			Message: fmt.Sprintf("evaluate TokenPolicies: %v", err),
+		}, 0)
+		if err != nil {
+			logger.Error(err, "failed to update status with policy evaluation failure")
+		}
+		return result, err
+	}
+	if len(violations) > 0 {
+		r.Metrics.RecordConfigError(ctx, controllerName, "policy")
+		logger.Info("token violates policy", "violations", violations)
+		// Policy, Namespace and Token changes all re-trigger reconciliation
+		// as well.
+		result, err := r.failed(ctx, owner, metav1.Condition{
+			Type:    githubv1.ConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  githubv1.ReasonPolicyViolation,
+			Message: strings.Join(violations, "; "),
+		}, 0)
+		if err != nil {
+			logger.Error(err, "failed to update status with policy violation")
+		}
+		return result, err
+	}
+
+	resolution := resolveApp(ctx, r.Client, r.Registry, owner.GetAppRef(), owner.GetNamespace())
 	if resolution.FailCondition != nil {
 		r.Metrics.RecordConfigError(ctx, controllerName, "ghapp")
 		logger.Info("App reference unavailable",
 			"reason", resolution.FailCondition.Reason,
 			"message", resolution.FailCondition.Message,
 		)
-		if owner.SetStatusCondition(*resolution.FailCondition) {
-			if err := r.Status().Update(ctx, owner); err != nil {
-				logger.Error(err, "failed to update status with AppRef failure")
-				return ctrl.Result{}, err
-			}
+		r.Events.Warning(owner, resolution.FailCondition.Reason, events.ActionResolveApp, resolution.FailCondition.Message)
+		result, err := r.failed(ctx, owner, *resolution.FailCondition, 0)
+		if err != nil {
+			logger.Error(err, "failed to update status with AppRef failure")
 		}
-		return ctrl.Result{RequeueAfter: resolution.RequeueAfter}, nil
+		return result, err
 	}
 
 	options := []tm.Option{
 		tm.WithClient(r.Client),
 		tm.WithGHApp(resolution.Client),
+		tm.WithGitHubClient(resolution.GitHub),
 		tm.WithLogger(logger),
 		tm.WithMetrics(r.Metrics),
+		tm.WithEvents(r.Events),
+		tm.WithSharedTokens(r.Registry.SharedTokens(resolution.Key)),
+		tm.WithRateLimit(r.Registry.RateLimit(resolution.Key)),
+		tm.WithBackoff(r.Backoff),
+	}
+
+	installationID := owner.GetInstallationID()
+	if selector := owner.GetInstallationSelector(); selector != nil {
+		var err error
+		installationID, err = r.Registry.Installation(ctx, resolution.Key, selector.String())
+		if err != nil {
+			r.Metrics.RecordConfigError(ctx, controllerName, "installation")
+			logger.Info("installation unavailable", "installation", selector.String(), "error", err.Error())
+			reason := githubv1.ReasonSetupFailed
+			if errors.Is(err, ghapp.ErrInstallationNotFound) {
+				reason = githubv1.ReasonInstallationNotFound
+			}
+			result, err := r.failed(ctx, owner, metav1.Condition{
+				Type:    githubv1.ConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  reason,
+				Message: err.Error(),
+			}, 0)
+			if err != nil {
+				logger.Error(err, "failed to update status with installation failure")
+			}
+			return result, err
+		}
+		options = append(options, tm.WithInstallationID(installationID))
+	}
+
+	exceeded, err := policy.CheckInstallation(ctx, r.Registry, resolution.Key, owner)
+	if err != nil {
+		// Not fatal: GitHub rejects any excess when the token is minted.
+		logger.Error(err, "failed to check installation permissions")
+	}
+	if exceeded != "" {
+		r.Metrics.RecordConfigError(ctx, controllerName, "permissions")
+		logger.Info("token exceeds installation permissions", "message", exceeded)
+		// Installation permissions change outside the cluster, so recheck
+		// no sooner than the App refreshes them.
+		result, err := r.failed(ctx, owner, metav1.Condition{
+			Type:    githubv1.ConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  githubv1.ReasonPermissionsExceeded,
+			Message: exceeded,
+		}, installationsRefreshInterval)
+		if err != nil {
+			logger.Error(err, "failed to update status with permissions exceeded")
+		}
+		return result, err
+	}
+
+	repositorySelector := owner.GetRepositorySelector()
+	if repositorySelector != nil {
+		if installationID == 0 {
+			installationID = resolution.Client.GetInstallationID()
+		}
+		repositories, err := selectRepositories(ctx, r.Registry, resolution.Key, installationID, repositorySelector)
+		if err != nil {
+			r.Metrics.RecordConfigError(ctx, controllerName, "repositories")
+			logger.Info("repository selection failed", "error", err.Error())
+			result, err := r.failed(ctx, owner, metav1.Condition{
+				Type:    githubv1.ConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  githubv1.ReasonRepositorySelectionFailed,
+				Message: err.Error(),
+			}, repositorySelectorResyncInterval)
+			if err != nil {
+				logger.Error(err, "failed to update status with repository selection failure")
+			}
+			return result, err
+		}
+		options = append(options, tm.WithRepositories(repositories))
 	}
 
 	tokenSecret := tm.NewTokenSecret(req.NamespacedName, owner, controllerName, options...)
@@ -87,6 +239,45 @@ func reconcileTokenLike[T any, PT interface {
 		logger.Error(err, "failed to reconcile token")
 		return result, err
 	}
+	if ready := meta.FindStatusCondition(owner.GetStatusConditions(), githubv1.ConditionTypeReady); ready != nil && ready.Reason == githubv1.ReasonInstallationNotFound {
+		// A cached selector resolution is stale, e.g. because the App was
+		// reinstalled: look it up afresh next time.
+		if installationID == 0 {
+			installationID = resolution.Client.GetInstallationID()
+		}
+		r.Registry.ForgetInstallation(resolution.Key, installationID)
+	}
+	if repositorySelector != nil && result.RequeueAfter > repositorySelectorResyncInterval {
+		// Repositories are created and retagged outside the cluster, so the
+		// selection is re-resolved, and the token re-minted if it changed,
+		// well before the token is due for refresh.
+		result.RequeueAfter = repositorySelectorResyncInterval
+	}
 	logger.Info("reconciled", "requeueAfter", result.RequeueAfter)
 	return result, nil
 }
+
+// selectRepositories returns the sorted names of the repositories accessible
+// to an installation of the App cached under key that selector matches.
+// Matching none is an error: a token limited to no repositories would
+// instead cover every repository of the installation.
+func selectRepositories(ctx context.Context, reg *ghapp.Registry, key ghapp.Key, installationID int64, selector *githubv1.RepositorySelector) ([]string, error) {
+	repositories, err := reg.InstallationRepositories(ctx, key, installationID, selector.NeedsCustomProperties())
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, repository := range repositories {
+		if selector.Matches(repository.Name, repository.Topics, repository.CustomProperties) {
+			names = append(names, repository.Name)
+		}
+	}
+	switch {
+	case len(names) == 0:
+		return nil, errors.New("repositorySelector matches no repositories of the installation")
+	case len(names) > githubv1.MaxTokenRepositories:
+		return nil, fmt.Errorf("repositorySelector matches %d repositories, more than the %d a token can be limited to", len(names), githubv1.MaxTokenRepositories)
+	}
+	slices.Sort(names)
+	return names, nil
+}
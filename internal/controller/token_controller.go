@@ -19,6 +19,7 @@ package controller
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -36,10 +37,16 @@ type TokenReconciler struct {
 	TokenReconcilerBase
 }
 
-// +kubebuilder:rbac:groups=github.as-code.io,resources=tokens,verbs=get;list;watch
+// +kubebuilder:rbac:groups=github.as-code.io,resources=tokens,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=github.as-code.io,resources=tokens/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=github.as-code.io,resources=tokens/finalizers,verbs=update
 // +kubebuilder:rbac:groups=github.as-code.io,resources=apps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=github.as-code.io,resources=appgrants,verbs=get;list;watch
+// +kubebuilder:rbac:groups=github.as-code.io,resources=tokenpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=github.as-code.io,resources=clustertokenpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -51,9 +58,10 @@ func (r *TokenReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	return reconcileTokenLike[githubv1.Token](ctx, &r.TokenReconcilerBase, req, ControllerNameToken)
 }
 
-// mapAppToTokens enqueues every Token in the App's namespace that references
-// it via spec.appRef.name. Tokens are namespaced and may only reference Apps
-// in their own namespace.
+// mapAppToTokens enqueues every Token that references the App via
+// spec.appRef, whether from the App's own namespace or, under an AppGrant,
+// from another. The field index resolves the Token namespace default, so a
+// single lookup suffices.
 func (r *TokenReconciler) mapAppToTokens(ctx context.Context, obj client.Object) []reconcile.Request {
 	app, ok := obj.(*githubv1.App)
 	if !ok {
@@ -61,8 +69,7 @@ func (r *TokenReconciler) mapAppToTokens(ctx context.Context, obj client.Object)
 	}
 	var list githubv1.TokenList
 	if err := r.List(ctx, &list,
-		client.InNamespace(app.Namespace),
-		client.MatchingFields{TokenAppRefIndex: app.Name},
+		client.MatchingFields{TokenAppRefIndex: app.Namespace + "/" + app.Name},
 	); err != nil {
 		log.FromContext(ctx).Error(err, "failed to list Tokens for App", "app", client.ObjectKeyFromObject(app))
 		return nil
@@ -74,15 +81,82 @@ func (r *TokenReconciler) mapAppToTokens(ctx context.Context, obj client.Object)
 	return requests
 }
 
+// mapAppGrantToTokens enqueues every Token that references an App in the
+// AppGrant's namespace from another namespace, so that granting or revoking
+// access takes effect immediately.
+func (r *TokenReconciler) mapAppGrantToTokens(ctx context.Context, obj client.Object) []reconcile.Request {
+	grant, ok := obj.(*githubv1.AppGrant)
+	if !ok {
+		return nil
+	}
+	var list githubv1.TokenList
+	if err := r.List(ctx, &list); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list Tokens for AppGrant", "appgrant", client.ObjectKeyFromObject(grant))
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range list.Items {
+		token := &list.Items[i]
+		if ref := token.GetAppRef(); ref != nil && ref.Namespace == grant.Namespace && token.Namespace != grant.Namespace {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(token)})
+		}
+	}
+	return requests
+}
+
+// mapPolicyToTokens enqueues every Token a TokenPolicy or ClusterTokenPolicy
+// may govern, or every Token in a Namespace whose labels changed (and with
+// them the policies and AppGrants that select it), so that such changes take
+// effect without waiting for the next refresh.
+func (r *TokenReconciler) mapPolicyToTokens(ctx context.Context, obj client.Object) []reconcile.Request {
+	var opts []client.ListOption
+	switch obj := obj.(type) {
+	case *githubv1.TokenPolicy:
+		opts = append(opts, client.InNamespace(obj.Namespace))
+	case *corev1.Namespace:
+		opts = append(opts, client.InNamespace(obj.Name))
+	case *githubv1.ClusterTokenPolicy:
+	default:
+		return nil
+	}
+	var list githubv1.TokenList
+	if err := r.List(ctx, &list, opts...); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list Tokens for policy", "object", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for i := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *TokenReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&githubv1.Token{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Named(ControllerNameToken).
+		Owns(&corev1.Secret{}).
 		Watches(&githubv1.App{},
 			handler.EnqueueRequestsFromMapFunc(r.mapAppToTokens),
 			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
 		).
+		Watches(&githubv1.AppGrant{},
+			handler.EnqueueRequestsFromMapFunc(r.mapAppGrantToTokens),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(&githubv1.TokenPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.mapPolicyToTokens),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(&githubv1.ClusterTokenPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.mapPolicyToTokens),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.mapPolicyToTokens),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 5}).
 		Complete(r)
 }
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	toolsevents "k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reasons of the events recorded on Tokens, ClusterTokens, Apps and
+// ClusterApps. Failures to resolve an App reference are recorded with the
+// reason of the resulting Ready condition.
+const (
+	ReasonSecretCreated       = "SecretCreated"
+	ReasonSecretRotated       = "SecretRotated"
+	ReasonSecretDeleted       = "SecretDeleted"
+	ReasonOwnershipConflict   = "OwnershipConflict"
+	ReasonTransientFailure    = "TransientFailure"
+	ReasonAppNotReady         = "AppNotReady"
+	ReasonKeyValidationFailed = "KeyValidationFailed"
+)
+
+// Actions of the events recorded, describing what the operator was doing.
+const (
+	ActionCreateSecret = "CreateSecret"
+	ActionUpdateSecret = "UpdateSecret"
+	ActionDeleteSecret = "DeleteSecret"
+	ActionMintToken    = "MintToken"
+	ActionResolveApp   = "ResolveApp"
+	ActionBuildClient  = "BuildClient"
+)
+
+const (
+	// DefaultInterval is how long an event is suppressed after it was last
+	// recorded for the same object with the same type, reason and note, so
+	// that a failure retried every minute is not recorded every minute.
+	DefaultInterval = 15 * time.Minute
+	// DefaultRotationInterval is how long rotation events are suppressed
+	// after one was last recorded for the same object. Rotation is routine,
+	// so the event stream need only show that it is still happening.
+	DefaultRotationInterval = 6 * time.Hour
+)
+
+// Recorder records Kubernetes events, dropping any identical to one recorded
+// for the same object within its suppression interval. All recording methods
+// are nil-receiver safe.
+type Recorder struct {
+	recorder         toolsevents.EventRecorder
+	interval         time.Duration
+	rotationInterval time.Duration
+	now              func() time.Time
+
+	mu       sync.Mutex
+	recorded map[eventKey]time.Time
+}
+
+type eventKey struct {
+	uid       types.UID
+	eventtype string
+	reason    string
+	note      string
+}
+
+// NewRecorder returns a Recorder that records events through recorder with
+// the default suppression intervals.
+func NewRecorder(recorder toolsevents.EventRecorder) *Recorder {
+	return &Recorder{
+		recorder:         recorder,
+		interval:         DefaultInterval,
+		rotationInterval: DefaultRotationInterval,
+		now:              time.Now,
+		recorded:         make(map[eventKey]time.Time),
+	}
+}
+
+// Normal records an event of type Normal on obj.
+func (r *Recorder) Normal(obj client.Object, reason, action, note string) {
+	r.record(obj, corev1.EventTypeNormal, reason, action, note)
+}
+
+// Warning records an event of type Warning on obj.
+func (r *Recorder) Warning(obj client.Object, reason, action, note string) {
+	r.record(obj, corev1.EventTypeWarning, reason, action, note)
+}
+
+func (r *Recorder) record(obj client.Object, eventtype, reason, action, note string) {
+	if r == nil {
+		return
+	}
+	interval := r.interval
+	if reason == ReasonSecretRotated {
+		interval = r.rotationInterval
+	}
+
+	key := eventKey{uid: obj.GetUID(), eventtype: eventtype, reason: reason, note: note}
+	now := r.now()
+	r.mu.Lock()
+	if last, ok := r.recorded[key]; ok && now.Sub(last) < interval {
+		r.mu.Unlock()
+		return
+	}
+	r.recorded[key] = now
+	r.prune(now)
+	r.mu.Unlock()
+
+	r.recorder.Eventf(obj, nil, eventtype, reason, action, "%s", note)
+}
+
+// prune forgets events whose suppression has lapsed, so that the events of
+// deleted objects do not accumulate. The caller must hold r.mu.
+func (r *Recorder) prune(now time.Time) {
+	for key, last := range r.recorded {
+		if now.Sub(last) >= max(r.interval, r.rotationInterval) {
+			delete(r.recorded, key)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolsevents "k8s.io/client-go/tools/events"
+)
+
+func TestNilRecorderSafety(t *testing.T) {
+	var r *Recorder
+	obj := &corev1.Secret{}
+
+	// All methods must be callable on a nil receiver without panic.
+	r.Normal(obj, ReasonSecretCreated, ActionCreateSecret, "created")
+	r.Warning(obj, ReasonTransientFailure, ActionMintToken, "failed")
+}
+
+func TestRecorder_Deduplicates(t *testing.T) {
+	fake := toolsevents.NewFakeRecorder(10)
+	r := NewRecorder(fake)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	a := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "a"}}
+	b := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "b"}}
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		record  func()
+		want    string
+	}{
+		{
+			name:   "first event recorded",
+			record: func() { r.Warning(a, ReasonTransientFailure, ActionMintToken, "timeout") },
+			want:   "Warning TransientFailure timeout",
+		},
+		{
+			name:    "repeat within interval suppressed",
+			advance: time.Minute,
+			record:  func() { r.Warning(a, ReasonTransientFailure, ActionMintToken, "timeout") },
+		},
+		{
+			name:   "different note recorded",
+			record: func() { r.Warning(a, ReasonTransientFailure, ActionMintToken, "reset") },
+			want:   "Warning TransientFailure reset",
+		},
+		{
+			name:   "different object recorded",
+			record: func() { r.Warning(b, ReasonTransientFailure, ActionMintToken, "timeout") },
+			want:   "Warning TransientFailure timeout",
+		},
+		{
+			name:    "repeat after interval recorded",
+			advance: DefaultInterval,
+			record:  func() { r.Warning(a, ReasonTransientFailure, ActionMintToken, "timeout") },
+			want:    "Warning TransientFailure timeout",
+		},
+		{
+			name:   "rotation recorded",
+			record: func() { r.Normal(a, ReasonSecretRotated, ActionUpdateSecret, "rotated") },
+			want:   "Normal SecretRotated rotated",
+		},
+		{
+			name:    "hourly rotation suppressed",
+			advance: time.Hour,
+			record:  func() { r.Normal(a, ReasonSecretRotated, ActionUpdateSecret, "rotated") },
+		},
+		{
+			name:    "rotation after rotation interval recorded",
+			advance: DefaultRotationInterval,
+			record:  func() { r.Normal(a, ReasonSecretRotated, ActionUpdateSecret, "rotated") },
+			want:    "Normal SecretRotated rotated",
+		},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		step.record()
+		var got string
+		select {
+		case got = <-fake.Events:
+		default:
+		}
+		if got != step.want {
+			t.Errorf("%s: got event %q, want %q", step.name, got, step.want)
+		}
+	}
+	if len(r.recorded) != 1 {
+		t.Errorf("recorded %d events, want lapsed events pruned to 1", len(r.recorded))
+	}
+}
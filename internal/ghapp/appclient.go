@@ -0,0 +1,70 @@
+package ghapp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/google/go-github/v84/github"
+	"github.com/isometry/ghait/v84"
+)
+
+// NewAppGitHubClient returns a go-github client for cfg's endpoint that
+// authenticates as the App with JWTs signed by signer, over cfg.HTTPClient.
+func NewAppGitHubClient(cfg *OperatorConfig, signer ghinstallation.Signer) (*github.Client, error) {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	transport, err := ghinstallation.NewAppsTransportWithOptions(base, cfg.AppID, ghinstallation.WithSigner(signer))
+	if err != nil {
+		return nil, err
+	}
+	signed := *httpClient
+	signed.Transport = transport
+	return NewGitHubClient(&signed, cfg.BaseURL, cfg.UploadURL)
+}
+
+// appClient mints installation tokens through an App-authenticated go-github
+// client, so that the App's endpoint, CA bundle and proxy apply to minting
+// whichever provider holds its key.
+type appClient struct {
+	appID          int64
+	installationID int64
+	github         *github.Client
+}
+
+var _ ghait.GHAIT = (*appClient)(nil)
+
+func (c *appClient) GetAppID() int64 {
+	return c.appID
+}
+
+func (c *appClient) GetInstallationID() int64 {
+	return c.installationID
+}
+
+// NewInstallationToken mints a token for installationID. Failures to reach
+// GitHub and server errors are returned as a [ghait.TransientError].
+func (c *appClient) NewInstallationToken(ctx context.Context, installationID int64, options *github.InstallationTokenOptions) (*github.InstallationToken, error) {
+	token, resp, err := c.github.Apps.CreateInstallationToken(ctx, installationID, options)
+	if err != nil {
+		if resp == nil || resp.StatusCode >= http.StatusInternalServerError {
+			return nil, ghait.TransientError{Err: err}
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+func (c *appClient) NewToken(ctx context.Context) (*github.InstallationToken, error) {
+	return c.NewInstallationToken(ctx, c.installationID, nil)
+}
+
+func (c *appClient) NewTokenWithOptions(ctx context.Context, options *github.InstallationTokenOptions) (*github.InstallationToken, error) {
+	return c.NewInstallationToken(ctx, c.installationID, options)
+}
@@ -3,6 +3,9 @@ package ghapp
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -20,6 +23,14 @@ type OperatorConfig struct {
 	Provider       string `mapstructure:"provider"`
 	Key            string `mapstructure:"key"`
 	ValidateKey    bool   `mapstructure:"validate_key"`
+	BaseURL        string `mapstructure:"base_url"`
+	UploadURL      string `mapstructure:"upload_url"`
+	CABundle       string `mapstructure:"ca_bundle"`
+	ProxyURL       string `mapstructure:"proxy_url"`
+
+	// HTTPClient is built by the [Registry] from CABundle and ProxyURL; nil
+	// means http.DefaultClient.
+	HTTPClient *http.Client `mapstructure:"-"`
 }
 
 func (c *OperatorConfig) GetAppID() int64 {
@@ -42,6 +53,22 @@ func (c *OperatorConfig) GetValidateKey() bool {
 	return c.ValidateKey
 }
 
+func (c *OperatorConfig) GetBaseURL() string {
+	return c.BaseURL
+}
+
+func (c *OperatorConfig) GetUploadURL() string {
+	return c.UploadURL
+}
+
+func (c *OperatorConfig) GetCABundle() string {
+	return c.CABundle
+}
+
+func (c *OperatorConfig) GetProxyURL() string {
+	return c.ProxyURL
+}
+
 // TokenValidity is the duration for which a token is valid. Always exactly 1 hour.
 const TokenValidity = time.Hour
 
@@ -55,6 +82,10 @@ func LoadConfig(ctx context.Context) (*OperatorConfig, error) {
 	_ = viper.BindEnv("installation_id", "GTM_INSTALLATION_ID", "GITHUB_INSTALLATION_ID")
 	_ = viper.BindEnv("provider", "GTM_PROVIDER", "KMS_PROVIDER")
 	_ = viper.BindEnv("key", "GTM_KEY", "KMS_KEY", "GITHUB_PRIVATE_KEY")
+	_ = viper.BindEnv("base_url", "GTM_BASE_URL", "GITHUB_API_URL")
+	_ = viper.BindEnv("upload_url", "GTM_UPLOAD_URL")
+	_ = viper.BindEnv("ca_bundle", "GTM_CA_BUNDLE")
+	_ = viper.BindEnv("proxy_url", "GTM_PROXY_URL")
 
 	viper.SetDefault("provider", "file")
 
@@ -75,5 +106,19 @@ func LoadConfig(ctx context.Context) (*OperatorConfig, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	if err := ValidateEndpoint(config.BaseURL, config.UploadURL); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// Like the file provider's key, ca_bundle may be a path rather than
+	// literal PEM, e.g. to a ConfigMap mounted alongside gtm.yaml.
+	if config.CABundle != "" && !strings.Contains(config.CABundle, "-----BEGIN") {
+		caBundle, err := os.ReadFile(config.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration: ca_bundle: %w", err)
+		}
+		config.CABundle = string(caBundle)
+	}
+
 	return config, nil
 }
@@ -0,0 +1,117 @@
+package ghapp
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// Endpoint is implemented by App configurations that may target a GitHub
+// Enterprise Server instance rather than github.com, or reach GitHub through
+// a proxy or a TLS-inspecting CA.
+type Endpoint interface {
+	GetBaseURL() string
+	GetUploadURL() string
+	GetCABundle() string
+	GetProxyURL() string
+}
+
+// EndpointVersion returns the component of a cached-client version string
+// contributed by an App's endpoint: empty for a direct connection to
+// github.com, so that existing versions are unchanged, and otherwise the
+// URLs and a digest of the CA bundle.
+func EndpointVersion(e Endpoint) string {
+	if e.GetBaseURL() == "" && e.GetUploadURL() == "" && e.GetCABundle() == "" && e.GetProxyURL() == "" {
+		return ""
+	}
+	var caDigest string
+	if e.GetCABundle() != "" {
+		sum := sha256.Sum256([]byte(e.GetCABundle()))
+		caDigest = hex.EncodeToString(sum[:8])
+	}
+	return "@" + strings.Join([]string{e.GetBaseURL(), e.GetUploadURL(), e.GetProxyURL(), caDigest}, ",")
+}
+
+// ValidateEndpoint checks that baseURL and uploadURL, when set, are absolute
+// http(s) URLs, and that uploadURL is only set alongside baseURL.
+func ValidateEndpoint(baseURL, uploadURL string) error {
+	if baseURL == "" {
+		if uploadURL != "" {
+			return errors.New("upload_url requires base_url")
+		}
+		return nil
+	}
+	if err := validateURL("base_url", baseURL); err != nil {
+		return err
+	}
+	if uploadURL == "" {
+		return nil
+	}
+	return validateURL("upload_url", uploadURL)
+}
+
+func validateURL(name, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%s: %q is not an absolute http(s) URL", name, raw)
+	}
+	return nil
+}
+
+// NewHTTPClient returns an HTTP client that trusts caBundle in addition to
+// the system roots and routes through proxyURL. It returns nil, meaning
+// http.DefaultClient, when both are empty.
+func NewHTTPClient(caBundle, proxyURL string) (*http.Client, error) {
+	if caBundle == "" && proxyURL == "" {
+		return nil, nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("proxy_url: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("proxy_url: unsupported scheme %q", u.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if caBundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(caBundle)) {
+			return nil, errors.New("ca_bundle contains no PEM-encoded certificates")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// NewGitHubClient returns a go-github client for the given endpoint. An empty
+// baseURL targets github.com; otherwise an empty uploadURL defaults to
+// baseURL, from which go-github derives the "/api/uploads/" path.
+func NewGitHubClient(httpClient *http.Client, baseURL, uploadURL string) (*github.Client, error) {
+	client := github.NewClient(httpClient)
+	if baseURL == "" {
+		return client, nil
+	}
+	if uploadURL == "" {
+		uploadURL = baseURL
+	}
+	return client.WithEnterpriseURLs(baseURL, uploadURL)
+}
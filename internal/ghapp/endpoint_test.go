@@ -0,0 +1,151 @@
+package ghapp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestValidateEndpoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseURL   string
+		uploadURL string
+		wantErr   bool
+	}{
+		{name: "github.com"},
+		{name: "base only", baseURL: "https://github.example.com/api/v3/"},
+		{name: "base and upload", baseURL: "https://github.example.com", uploadURL: "https://github.example.com/api/uploads/"},
+		{name: "upload without base", uploadURL: "https://github.example.com", wantErr: true},
+		{name: "relative base", baseURL: "github.example.com", wantErr: true},
+		{name: "unsupported scheme", baseURL: "ftp://github.example.com", wantErr: true},
+		{name: "invalid upload", baseURL: "https://github.example.com", uploadURL: "://", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEndpoint(tt.baseURL, tt.uploadURL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEndpoint(%q, %q) err = %v, wantErr %v", tt.baseURL, tt.uploadURL, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewGitHubClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseURL    string
+		uploadURL  string
+		wantBase   string
+		wantUpload string
+	}{
+		{
+			name:       "github.com",
+			wantBase:   "https://api.github.com/",
+			wantUpload: "https://uploads.github.com/",
+		},
+		{
+			name:       "bare host",
+			baseURL:    "https://github.example.com",
+			wantBase:   "https://github.example.com/api/v3/",
+			wantUpload: "https://github.example.com/api/uploads/",
+		},
+		{
+			name:       "explicit upload",
+			baseURL:    "https://github.example.com/api/v3/",
+			uploadURL:  "https://uploads.example.com/api/uploads/",
+			wantBase:   "https://github.example.com/api/v3/",
+			wantUpload: "https://uploads.example.com/api/uploads/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewGitHubClient(nil, tt.baseURL, tt.uploadURL)
+			if err != nil {
+				t.Fatalf("NewGitHubClient() err = %v", err)
+			}
+			if got := client.BaseURL.String(); got != tt.wantBase {
+				t.Errorf("BaseURL = %q, want %q", got, tt.wantBase)
+			}
+			if got := client.UploadURL.String(); got != tt.wantUpload {
+				t.Errorf("UploadURL = %q, want %q", got, tt.wantUpload)
+			}
+		})
+	}
+}
+
+func TestEndpointVersion(t *testing.T) {
+	if got := EndpointVersion(&OperatorConfig{}); got != "" {
+		t.Errorf("EndpointVersion(github.com) = %q, want empty", got)
+	}
+	versions := map[string]bool{}
+	for _, cfg := range []*OperatorConfig{
+		{BaseURL: "https://a.example.com"},
+		{BaseURL: "https://b.example.com"},
+		{ProxyURL: "http://proxy.example.com:3128"},
+		{CABundle: "bundle-1"},
+		{CABundle: "bundle-2"},
+	} {
+		v := EndpointVersion(cfg)
+		if v == "" || versions[v] {
+			t.Errorf("EndpointVersion(%+v) = %q; want distinct non-empty value", cfg, v)
+		}
+		versions[v] = true
+	}
+}
+
+func testCABundle(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	client, err := NewHTTPClient("", "")
+	if err != nil || client != nil {
+		t.Fatalf("NewHTTPClient(\"\", \"\") = %v, %v; want nil, nil", client, err)
+	}
+
+	if _, err := NewHTTPClient("not a certificate", ""); err == nil {
+		t.Error("NewHTTPClient() with invalid CA bundle returned no error")
+	}
+	if _, err := NewHTTPClient("", "ftp://proxy.example.com"); err == nil {
+		t.Error("NewHTTPClient() with unsupported proxy scheme returned no error")
+	}
+
+	client, err = NewHTTPClient(testCABundle(t), "http://proxy.example.com:3128")
+	if err != nil {
+		t.Fatalf("NewHTTPClient() err = %v", err)
+	}
+	transport := client.Transport.(*http.Transport)
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.RootCAs == nil {
+		t.Error("NewHTTPClient() did not configure RootCAs")
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/", nil)
+	proxy, err := transport.Proxy(req)
+	if err != nil || proxy == nil || proxy.Host != "proxy.example.com:3128" {
+		t.Errorf("Proxy() = %v, %v; want proxy.example.com:3128", proxy, err)
+	}
+}
@@ -0,0 +1,212 @@
+package ghapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// AppsAPI is the subset of the App-authenticated (JWT) GitHub API used by the
+// operator. [*github.AppsService] satisfies it.
+type AppsAPI interface {
+	FindOrganizationInstallation(ctx context.Context, org string) (*github.Installation, *github.Response, error)
+	FindUserInstallation(ctx context.Context, user string) (*github.Installation, *github.Response, error)
+	FindRepositoryInstallation(ctx context.Context, owner, repo string) (*github.Installation, *github.Response, error)
+	ListInstallations(ctx context.Context, opts *github.ListOptions) ([]*github.Installation, *github.Response, error)
+	GetInstallation(ctx context.Context, id int64) (*github.Installation, *github.Response, error)
+}
+
+// AppsFactoryFunc returns the Apps API of app, the App-authenticated client
+// built for an App by [NewAppGitHubClient]. It is a variable on the
+// [Registry] so tests can inject a fake.
+type AppsFactoryFunc func(app *github.Client) (AppsAPI, error)
+
+// ErrInstallationNotFound is returned when no installation of the App
+// matches a selector.
+var ErrInstallationNotFound = errors.New("installation not found")
+
+var defaultAppsFactory AppsFactoryFunc = func(app *github.Client) (AppsAPI, error) {
+	return app.Apps, nil
+}
+
+// WithAppsFactory replaces the default App-authenticated API factory.
+// Intended for tests.
+func WithAppsFactory(f AppsFactoryFunc) Option {
+	return func(r *Registry) {
+		r.appsFactory = f
+	}
+}
+
+// FindInstallation returns the ID of the App's installation on selector: a
+// repository as "owner/name", or otherwise an account login, tried first as
+// an organization and then as a user.
+func FindInstallation(ctx context.Context, apps AppsAPI, selector string) (int64, error) {
+	var (
+		installation *github.Installation
+		resp         *github.Response
+		err          error
+	)
+	if owner, repo, ok := strings.Cut(selector, "/"); ok {
+		installation, resp, err = apps.FindRepositoryInstallation(ctx, owner, repo)
+	} else {
+		installation, resp, err = apps.FindOrganizationInstallation(ctx, selector)
+		if isNotFound(resp) {
+			installation, resp, err = apps.FindUserInstallation(ctx, selector)
+		}
+	}
+	if isNotFound(resp) {
+		return 0, fmt.Errorf("%w for %s", ErrInstallationNotFound, selector)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("look up installation for %s: %w", selector, err)
+	}
+	return installation.GetID(), nil
+}
+
+func isNotFound(resp *github.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
+}
+
+// Installation resolves selector (see [FindInstallation]) to an installation
+// ID of the App cached under key. Results are cached until the App's client
+// is rebuilt or invalidated.
+func (r *Registry) Installation(ctx context.Context, key Key, selector string) (int64, error) {
+	r.mu.RLock()
+	cached, ok := r.clients[key]
+	id, resolved := r.installations[key][selector]
+	r.mu.RUnlock()
+	if !ok {
+		return 0, fmt.Errorf("App %s: client not yet cached", key)
+	}
+	if resolved {
+		return id, nil
+	}
+
+	if cached.appsErr != nil {
+		return 0, cached.appsErr
+	}
+	id, err := FindInstallation(ctx, cached.apps, selector)
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Only cache against the client the lookup was made with.
+	if current, ok := r.clients[key]; ok && current.client == cached.client {
+		if r.installations[key] == nil {
+			r.installations[key] = make(map[string]int64)
+		}
+		r.installations[key][selector] = id
+	}
+	return id, nil
+}
+
+// ForgetInstallation drops every selector of the App cached under key that
+// resolved to installationID, so that it is looked up afresh, e.g. after
+// GitHub reports the installation gone because the App was reinstalled.
+func (r *Registry) ForgetInstallation(key Key, installationID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	maps.DeleteFunc(r.installations[key], func(_ string, id int64) bool {
+		return id == installationID
+	})
+}
+
+// Installations lists every installation of the App cached under key.
+func (r *Registry) Installations(ctx context.Context, key Key) ([]*github.Installation, error) {
+	cached, ok := r.lookupCached(key)
+	if !ok {
+		return nil, fmt.Errorf("App %s: client not yet cached", key)
+	}
+	if cached.appsErr != nil {
+		return nil, cached.appsErr
+	}
+
+	var installations []*github.Installation
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := cached.apps.ListInstallations(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("list installations: %w", err)
+		}
+		installations = append(installations, page...)
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current, ok := r.clients[key]; ok && current.client == cached.client {
+		r.permissions[key] = make(map[int64]*github.InstallationPermissions, len(installations))
+		for _, installation := range installations {
+			r.permissions[key][installation.GetID()] = installation.GetPermissions()
+		}
+	}
+	return installations, nil
+}
+
+// InstallationPermissions returns the permissions granted to an installation
+// of the App cached under key. They are fetched once and cached until the
+// App's client is rebuilt or invalidated, or refreshed by
+// [Registry.Installations].
+func (r *Registry) InstallationPermissions(ctx context.Context, key Key, installationID int64) (*github.InstallationPermissions, error) {
+	r.mu.RLock()
+	cached, ok := r.clients[key]
+	permissions, known := r.permissions[key][installationID]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("App %s: client not yet cached", key)
+	}
+	if known {
+		return permissions, nil
+	}
+
+	if cached.appsErr != nil {
+		return nil, cached.appsErr
+	}
+	installation, resp, err := cached.apps.GetInstallation(ctx, installationID)
+	if isNotFound(resp) {
+		return nil, fmt.Errorf("%w: %d", ErrInstallationNotFound, installationID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get installation %d: %w", installationID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current, ok := r.clients[key]; ok && current.client == cached.client {
+		if r.permissions[key] == nil {
+			r.permissions[key] = make(map[int64]*github.InstallationPermissions)
+		}
+		r.permissions[key][installationID] = installation.GetPermissions()
+	}
+	return installation.GetPermissions(), nil
+}
+
+// PermissionMap returns the permissions set in p as a map from permission
+// name (e.g. "contents") to level.
+func PermissionMap(p *github.InstallationPermissions) map[string]string {
+	if p == nil {
+		return nil
+	}
+	permissions := make(map[string]string)
+	v := reflect.ValueOf(p).Elem()
+	for i := range v.NumField() {
+		level, ok := v.Field(i).Interface().(*string)
+		if !ok || level == nil {
+			continue
+		}
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		permissions[name] = *level
+	}
+	return permissions
+}
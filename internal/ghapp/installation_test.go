@@ -0,0 +1,260 @@
+package ghapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// fakeApps serves installations from maps keyed by org, user and
+// "owner/repo", and their permissions by ID, counting lookups.
+type fakeApps struct {
+	orgs, users, repos map[string]int64
+	permissions        map[int64]*github.InstallationPermissions
+	pages              [][]*github.Installation
+	calls              int
+}
+
+func (f *fakeApps) find(m map[string]int64, key string) (*github.Installation, *github.Response, error) {
+	f.calls++
+	if id, ok := m[key]; ok {
+		return &github.Installation{ID: github.Ptr(id)}, &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}, nil
+	}
+	resp := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: "Not Found"}
+}
+
+func (f *fakeApps) FindOrganizationInstallation(_ context.Context, org string) (*github.Installation, *github.Response, error) {
+	return f.find(f.orgs, org)
+}
+
+func (f *fakeApps) FindUserInstallation(_ context.Context, user string) (*github.Installation, *github.Response, error) {
+	return f.find(f.users, user)
+}
+
+func (f *fakeApps) FindRepositoryInstallation(_ context.Context, owner, repo string) (*github.Installation, *github.Response, error) {
+	return f.find(f.repos, owner+"/"+repo)
+}
+
+func (f *fakeApps) ListInstallations(_ context.Context, opts *github.ListOptions) ([]*github.Installation, *github.Response, error) {
+	f.calls++
+	page := max(opts.Page, 1)
+	resp := &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}
+	if page < len(f.pages) {
+		resp.NextPage = page + 1
+	}
+	return f.pages[page-1], resp, nil
+}
+
+func (f *fakeApps) GetInstallation(_ context.Context, id int64) (*github.Installation, *github.Response, error) {
+	f.calls++
+	if p, ok := f.permissions[id]; ok {
+		return &github.Installation{ID: github.Ptr(id), Permissions: p}, &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}, nil
+	}
+	resp := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: "Not Found"}
+}
+
+func TestFindInstallation(t *testing.T) {
+	apps := &fakeApps{
+		orgs:  map[string]int64{"acme": 1},
+		users: map[string]int64{"octocat": 2},
+		repos: map[string]int64{"acme/widgets": 3},
+	}
+	tests := []struct {
+		selector string
+		want     int64
+		wantErr  error
+	}{
+		{selector: "acme", want: 1},
+		{selector: "octocat", want: 2},
+		{selector: "acme/widgets", want: 3},
+		{selector: "nobody", wantErr: ErrInstallationNotFound},
+		{selector: "acme/gadgets", wantErr: ErrInstallationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := FindInstallation(context.Background(), apps, tt.selector)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FindInstallation() err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FindInstallation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Installation_CachesUntilRebuild(t *testing.T) {
+	apps := &fakeApps{orgs: map[string]int64{"acme": 7}}
+	fac, _ := countingFactory()
+	r := NewRegistry("gtm-system", nil,
+		WithFactory(fac),
+		WithAppsFactory(func(*github.Client) (AppsAPI, error) { return apps, nil }),
+	)
+	ctx := context.Background()
+	key := Key{Namespace: "team-a", Name: "prod"}
+
+	if _, err := r.Installation(ctx, key, "acme"); err == nil {
+		t.Fatal("Installation() before the client is cached returned no error")
+	}
+
+	cfg := &OperatorConfig{AppID: 42, Provider: "file", Key: testAppKey(t)}
+	if _, err := r.ForApp(ctx, key, "1", cfg); err != nil {
+		t.Fatalf("ForApp() err = %v", err)
+	}
+	for range 2 {
+		id, err := r.Installation(ctx, key, "acme")
+		if err != nil || id != 7 {
+			t.Fatalf("Installation() = %d, %v; want 7, nil", id, err)
+		}
+	}
+	if apps.calls != 1 {
+		t.Errorf("lookups = %d, want 1 (cached)", apps.calls)
+	}
+
+	if _, err := r.ForApp(ctx, key, "2", cfg); err != nil {
+		t.Fatalf("ForApp() err = %v", err)
+	}
+	if _, err := r.Installation(ctx, key, "acme"); err != nil {
+		t.Fatalf("Installation() after rebuild err = %v", err)
+	}
+	if apps.calls != 2 {
+		t.Errorf("lookups after rebuild = %d, want 2", apps.calls)
+	}
+}
+
+func TestRegistry_ForgetInstallation(t *testing.T) {
+	apps := &fakeApps{orgs: map[string]int64{"acme": 7, "other": 8}}
+	fac, _ := countingFactory()
+	r := NewRegistry("gtm-system", nil,
+		WithFactory(fac),
+		WithAppsFactory(func(*github.Client) (AppsAPI, error) { return apps, nil }),
+	)
+	ctx := context.Background()
+	key := Key{Namespace: "team-a", Name: "prod"}
+	if _, err := r.ForApp(ctx, key, "1", &OperatorConfig{AppID: 42, Provider: "file", Key: testAppKey(t)}); err != nil {
+		t.Fatalf("ForApp() err = %v", err)
+	}
+	for _, selector := range []string{"acme", "other"} {
+		if _, err := r.Installation(ctx, key, selector); err != nil {
+			t.Fatalf("Installation(%q) err = %v", selector, err)
+		}
+	}
+
+	// The App was reinstalled on acme.
+	apps.orgs["acme"] = 9
+	r.ForgetInstallation(key, 7)
+
+	if id, err := r.Installation(ctx, key, "acme"); err != nil || id != 9 {
+		t.Fatalf("Installation(acme) = %d, %v; want 9, nil", id, err)
+	}
+	if _, err := r.Installation(ctx, key, "other"); err != nil {
+		t.Fatalf("Installation(other) err = %v", err)
+	}
+	if apps.calls != 3 {
+		t.Errorf("lookups = %d, want 3 (only the forgotten selector looked up again)", apps.calls)
+	}
+}
+
+func TestRegistry_Installations_Paginates(t *testing.T) {
+	apps := &fakeApps{pages: [][]*github.Installation{
+		{{ID: github.Ptr(int64(1))}, {ID: github.Ptr(int64(2))}},
+		{{ID: github.Ptr(int64(3))}},
+	}}
+	fac, _ := countingFactory()
+	r := NewRegistry("gtm-system", nil,
+		WithFactory(fac),
+		WithAppsFactory(func(*github.Client) (AppsAPI, error) { return apps, nil }),
+	)
+	ctx := context.Background()
+	key := Key{Namespace: "team-a", Name: "prod"}
+	if _, err := r.ForApp(ctx, key, "1", &OperatorConfig{AppID: 42, Provider: "file", Key: testAppKey(t)}); err != nil {
+		t.Fatalf("ForApp() err = %v", err)
+	}
+
+	installations, err := r.Installations(ctx, key)
+	if err != nil {
+		t.Fatalf("Installations() err = %v", err)
+	}
+	if len(installations) != 3 || apps.calls != 2 {
+		t.Errorf("Installations() = %d installations in %d calls, want 3 in 2", len(installations), apps.calls)
+	}
+}
+
+func TestRegistry_InstallationPermissions(t *testing.T) {
+	apps := &fakeApps{permissions: map[int64]*github.InstallationPermissions{
+		7: {Contents: github.Ptr("read")},
+	}}
+	fac, _ := countingFactory()
+	r := NewRegistry("gtm-system", nil,
+		WithFactory(fac),
+		WithAppsFactory(func(*github.Client) (AppsAPI, error) { return apps, nil }),
+	)
+	ctx := context.Background()
+	key := Key{Namespace: "team-a", Name: "prod"}
+	if _, err := r.ForApp(ctx, key, "1", &OperatorConfig{AppID: 42, Provider: "file", Key: testAppKey(t)}); err != nil {
+		t.Fatalf("ForApp() err = %v", err)
+	}
+
+	for range 2 {
+		p, err := r.InstallationPermissions(ctx, key, 7)
+		if err != nil || p.GetContents() != "read" {
+			t.Fatalf("InstallationPermissions() = %v, %v; want contents=read", p, err)
+		}
+	}
+	if apps.calls != 1 {
+		t.Errorf("lookups = %d, want 1 (cached)", apps.calls)
+	}
+	if _, err := r.InstallationPermissions(ctx, key, 8); !errors.Is(err, ErrInstallationNotFound) {
+		t.Errorf("InstallationPermissions(8) err = %v, want ErrInstallationNotFound", err)
+	}
+}
+
+func TestPermissionMap(t *testing.T) {
+	if got := PermissionMap(nil); got != nil {
+		t.Errorf("PermissionMap(nil) = %v, want nil", got)
+	}
+	got := PermissionMap(&github.InstallationPermissions{
+		Contents: github.Ptr("write"),
+		Metadata: github.Ptr("read"),
+	})
+	if len(got) != 2 || got["contents"] != "write" || got["metadata"] != "read" {
+		t.Errorf("PermissionMap() = %v, want contents=write, metadata=read", got)
+	}
+}
+
+func TestRegistry_Installation_AppClient(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		auth = req.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		if req.URL.Path == "/api/v3/orgs/acme/installation" {
+			_, _ = w.Write([]byte(`{"id":9}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	}))
+	defer srv.Close()
+
+	r := NewRegistry("gtm-system", nil)
+	ctx := context.Background()
+	key := Key{Namespace: "team-a", Name: "ghes"}
+	cfg := &OperatorConfig{AppID: 42, Provider: "file", Key: testAppKey(t), BaseURL: srv.URL + "/api/v3/"}
+	if _, err := r.ForApp(ctx, key, "1", cfg); err != nil {
+		t.Fatalf("ForApp() err = %v", err)
+	}
+	id, err := r.Installation(ctx, key, "acme")
+	if err != nil || id != 9 {
+		t.Fatalf("Installation() = %d, %v; want 9", id, err)
+	}
+	if !strings.HasPrefix(auth, "Bearer ") || strings.Count(auth, ".") != 2 {
+		t.Errorf("Authorization = %q, want an App JWT", auth)
+	}
+}
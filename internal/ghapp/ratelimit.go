@@ -0,0 +1,158 @@
+package ghapp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// lowRateLimitPercent is the share of an App's rate limit below which mints
+// are paced so that the remaining budget lasts until the window resets.
+const lowRateLimitPercent = 10
+
+// RateLimitedError is returned instead of minting a token while the App's
+// rate limit budget is exhausted, low, or GitHub has asked it to back off.
+type RateLimitedError struct {
+	Key Key
+	// RetryAfter is how long to wait before minting again.
+	RetryAfter time.Duration
+	// Remaining is the number of requests left in the rate limit window.
+	Remaining int
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("App %s: GitHub API rate limit budget low (%d requests remaining), deferring mint for %s", e.Key, e.Remaining, e.RetryAfter)
+}
+
+// RateLimitState is the rate limit of an App as last reported by GitHub.
+type RateLimitState struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimit tracks the rate limit budget of one App from the
+// X-RateLimit-* and Retry-After headers of the responses to its
+// App-authenticated requests. All methods are nil-receiver safe.
+type RateLimit struct {
+	key Key
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    RateLimitState
+	known    bool
+	retryAt  time.Time
+	nextMint time.Time
+}
+
+func newRateLimit(key Key) *RateLimit {
+	return &RateLimit{key: key, now: time.Now}
+}
+
+// RateLimit returns the rate limit tracker of the App cached under key,
+// shared by every client built for it.
+func (r *Registry) RateLimit(key Key) *RateLimit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rateLimit(key)
+}
+
+// rateLimit is [Registry.RateLimit] for callers holding r.mu for writing.
+func (r *Registry) rateLimit(key Key) *RateLimit {
+	limit, ok := r.rateLimits[key]
+	if !ok {
+		limit = newRateLimit(key)
+		r.rateLimits[key] = limit
+	}
+	return limit
+}
+
+// Key returns the key of the App whose rate limit is tracked.
+func (l *RateLimit) Key() Key {
+	if l == nil {
+		return Key{}
+	}
+	return l.key
+}
+
+// State returns the rate limit last reported by GitHub, if any.
+func (l *RateLimit) State() (RateLimitState, bool) {
+	if l == nil {
+		return RateLimitState{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state, l.known
+}
+
+// Reserve claims the budget for a mint, returning nil if it may go ahead
+// now, or a [RateLimitedError] saying how long to wait. Mints wait out any
+// Retry-After GitHub asked for and an exhausted budget. Once less than
+// lowRateLimitPercent of the budget remains, they are spaced evenly over
+// what is left of the window, so that a burst of mints cannot exhaust it.
+func (l *RateLimit) Reserve() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	wait := l.retryAt.Sub(now)
+	if l.known && l.state.Reset.After(now) && l.state.Remaining*100 < l.state.Limit*lowRateLimitPercent {
+		untilReset := l.state.Reset.Sub(now)
+		if l.state.Remaining == 0 {
+			wait = max(wait, untilReset)
+		} else {
+			if l.nextMint.After(now) {
+				wait = max(wait, l.nextMint.Sub(now))
+			}
+			if wait <= 0 {
+				l.nextMint = now.Add(untilReset / time.Duration(l.state.Remaining))
+			}
+		}
+	}
+	if wait <= 0 {
+		return nil
+	}
+	return &RateLimitedError{Key: l.key, RetryAfter: wait, Remaining: l.state.Remaining}
+}
+
+// observe records the rate limit reported by response.
+func (l *RateLimit) observe(response *http.Response) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, limitErr := strconv.Atoi(response.Header.Get("X-RateLimit-Limit"))
+	remaining, remainingErr := strconv.Atoi(response.Header.Get("X-RateLimit-Remaining"))
+	reset, resetErr := strconv.ParseInt(response.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if limitErr == nil && remainingErr == nil && resetErr == nil {
+		l.state = RateLimitState{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}
+		l.known = true
+	}
+
+	switch response.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			l.retryAt = now.Add(time.Duration(seconds) * time.Second)
+		}
+	}
+}
+
+// rateLimitTransport records the rate limit reported by every response to
+// requests made through base.
+type rateLimitTransport struct {
+	base  http.RoundTripper
+	limit *RateLimit
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	response, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.limit.observe(response)
+	}
+	return response, err
+}
@@ -0,0 +1,204 @@
+package ghapp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimit_Reserve(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var header http.Header
+	var status int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	r := NewRegistry("gtm-system", nil)
+	key := Key{Namespace: "team-a", Name: "prod"}
+	limit := r.RateLimit(key)
+	limit.now = func() time.Time { return now }
+	if r.RateLimit(key) != limit {
+		t.Fatal("RateLimit() returned a new tracker for the same key")
+	}
+	client := &http.Client{Transport: &rateLimitTransport{base: http.DefaultTransport, limit: limit}}
+	respond := func(code int, remaining int, extra ...string) {
+		t.Helper()
+		status = code
+		header = http.Header{}
+		header.Set("X-RateLimit-Limit", "5000")
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(10*time.Minute).Unix(), 10))
+		for i := 0; i+1 < len(extra); i += 2 {
+			header.Set(extra[i], extra[i+1])
+		}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	reserve := func() time.Duration {
+		t.Helper()
+		err := limit.Reserve()
+		if err == nil {
+			return 0
+		}
+		limitErr := (*RateLimitedError)(nil)
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("Reserve() err = %v, want a RateLimitedError", err)
+		}
+		return limitErr.RetryAfter
+	}
+
+	if _, ok := limit.State(); ok {
+		t.Error("State() known before any response")
+	}
+	if wait := reserve(); wait != 0 {
+		t.Errorf("Reserve() with an unknown budget waits %v, want 0", wait)
+	}
+
+	respond(http.StatusCreated, 4000)
+	if state, ok := limit.State(); !ok || state.Remaining != 4000 || state.Limit != 5000 {
+		t.Errorf("State() = %+v, %v, want 4000 of 5000 remaining", state, ok)
+	}
+	for range 3 {
+		if wait := reserve(); wait != 0 {
+			t.Errorf("Reserve() with a healthy budget waits %v, want 0", wait)
+		}
+	}
+
+	// Below 10% of the budget, mints are spread over the rest of the window.
+	respond(http.StatusCreated, 100)
+	if wait := reserve(); wait != 0 {
+		t.Errorf("first Reserve() with a low budget waits %v, want 0", wait)
+	}
+	if wait := reserve(); wait != 6*time.Second {
+		t.Errorf("second Reserve() with a low budget waits %v, want 6s", wait)
+	}
+	now = now.Add(6 * time.Second)
+	if wait := reserve(); wait != 0 {
+		t.Errorf("Reserve() once paced waits %v, want 0", wait)
+	}
+
+	respond(http.StatusForbidden, 0)
+	if wait := reserve(); wait != 10*time.Minute {
+		t.Errorf("Reserve() with an exhausted budget waits %v, want until the reset", wait)
+	}
+
+	respond(http.StatusTooManyRequests, 4000, "Retry-After", "30")
+	if wait := reserve(); wait != 30*time.Second {
+		t.Errorf("Reserve() after Retry-After waits %v, want 30s", wait)
+	}
+	now = now.Add(30 * time.Second)
+	if wait := reserve(); wait != 0 {
+		t.Errorf("Reserve() after Retry-After elapsed waits %v, want 0", wait)
+	}
+
+	r.Invalidate(key)
+	if r.RateLimit(key) == limit {
+		t.Error("RateLimit() after Invalidate() returned the old tracker")
+	}
+
+	var nilLimit *RateLimit
+	if err := nilLimit.Reserve(); err != nil {
+		t.Errorf("nil Reserve() err = %v", err)
+	}
+}
+
+// roundTripFunc adapts a function to [http.RoundTripper].
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRateLimit_TracksGitHubComMints(t *testing.T) {
+	remaining := 5000
+	var hosts []string
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+		remaining--
+		header := http.Header{}
+		header.Set("Content-Type", "application/json")
+		header.Set("X-RateLimit-Limit", "5000")
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader(`{"token":"ghs_minted","expires_at":"2030-01-01T00:00:00Z"}`)),
+			Request:    req,
+		}, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+
+	r := NewRegistry("gtm-system", &OperatorConfig{AppID: 42, InstallationID: 7, Provider: "file", Key: testAppKey(t)})
+	client, err := r.Startup(context.Background())
+	if err != nil {
+		t.Fatalf("Startup() err = %v", err)
+	}
+	limit := r.RateLimit(StartupKey)
+	for _, want := range []int{4999, 4998} {
+		if _, err := client.NewToken(context.Background()); err != nil {
+			t.Fatalf("NewToken() err = %v", err)
+		}
+		if state, ok := limit.State(); !ok || state.Remaining != want {
+			t.Errorf("State() after mint = %+v, %v, want %d remaining", state, ok, want)
+		}
+	}
+	for _, host := range hosts {
+		if host != "api.github.com" {
+			t.Errorf("minted against %q, want api.github.com", host)
+		}
+	}
+}
+
+func TestRateLimit_TracksKMSProviderMints(t *testing.T) {
+	remaining := 10
+	var auth []string
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		auth = append(auth, req.Header.Get("Authorization"))
+		remaining--
+		header := http.Header{}
+		header.Set("Content-Type", "application/json")
+		header.Set("X-RateLimit-Limit", "5000")
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader(`{"token":"ghs_minted","expires_at":"2030-01-01T00:00:00Z"}`)),
+			Request:    req,
+		}, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+
+	r := NewRegistry("gtm-system", &OperatorConfig{AppID: 42, InstallationID: 7, Provider: "aws", Key: "alias/app"},
+		WithSigner(kmsSigner(t, fakeSigner{})))
+	client, err := r.Startup(context.Background())
+	if err != nil {
+		t.Fatalf("Startup() err = %v", err)
+	}
+	if _, err := client.NewToken(context.Background()); err != nil {
+		t.Fatalf("NewToken() err = %v", err)
+	}
+	if state, ok := r.RateLimit(StartupKey).State(); !ok || state.Remaining != 9 {
+		t.Errorf("State() after mint = %+v, %v, want 9 remaining", state, ok)
+	}
+	if len(auth) != 1 || auth[0] != "Bearer kms-signed" {
+		t.Errorf("Authorization = %v, want the JWT signed by the KMS provider", auth)
+	}
+}
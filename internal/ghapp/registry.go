@@ -20,28 +20,48 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 
+	"github.com/google/go-github/v84/github"
 	"github.com/isometry/ghait/v84"
+	"golang.org/x/sync/singleflight"
 )
 
 // Key identifies an App in the registry. The zero value is reserved for the
-// startup-config singleton (see [Registry.Startup]).
+// startup-config singleton (see [Registry.Startup]); a cluster-scoped
+// ClusterApp is keyed by Name alone.
 type Key struct {
 	Namespace string
 	Name      string
 }
 
+// String returns "namespace/name" for an App, "name" for a ClusterApp, and
+// "startup" for [StartupKey].
+func (k Key) String() string {
+	switch {
+	case k == StartupKey:
+		return "startup"
+	case k.Namespace == "":
+		return k.Name
+	}
+	return k.Namespace + "/" + k.Name
+}
+
 // StartupKey is the reserved key for the operator's startup-config client.
 var StartupKey = Key{}
 
-// FactoryFunc constructs a [ghait.GHAIT] client from a [ghait.Config]. It is
-// a variable on the [Registry] so tests can inject a fake without touching
-// network or KMS providers.
-type FactoryFunc func(ctx context.Context, cfg ghait.Config) (ghait.GHAIT, error)
+// FactoryFunc constructs a [ghait.GHAIT] client for cfg. app is the
+// App-authenticated client for cfg's endpoint built by
+// [NewAppGitHubClient]. It is a variable on the [Registry] so tests can
+// inject a fake without touching the network.
+type FactoryFunc func(ctx context.Context, cfg *OperatorConfig, app *github.Client) (ghait.GHAIT, error)
 
-var defaultFactory FactoryFunc = func(ctx context.Context, cfg ghait.Config) (ghait.GHAIT, error) {
-	return ghait.NewGHAIT(ctx, cfg)
+// defaultFactory mints through app, so that the App's endpoint, CA bundle
+// and proxy apply to minting and every mint response feeds its [RateLimit],
+// whichever provider holds its key.
+var defaultFactory FactoryFunc = func(_ context.Context, cfg *OperatorConfig, app *github.Client) (ghait.GHAIT, error) {
+	return &appClient{appID: cfg.AppID, installationID: cfg.InstallationID, github: app}, nil
 }
 
 // ErrNoStartupConfig is returned by [Registry.Startup] when the operator was
@@ -51,7 +71,13 @@ var ErrNoStartupConfig = errors.New("no startup GitHub App configuration loaded;
 
 type cachedClient struct {
 	client  ghait.GHAIT
+	github  *github.Client
 	version string
+
+	// apps is the App-authenticated API, or nil with appsErr explaining why
+	// it is unavailable.
+	apps    AppsAPI
+	appsErr error
 }
 
 // Registry caches [ghait.GHAIT] clients keyed by App identity. The startup
@@ -63,6 +89,15 @@ type Registry struct {
 	startupCfg *OperatorConfig
 	operatorNS string
 	factory    FactoryFunc
+	signer     SignerFunc
+
+	appsFactory   AppsFactoryFunc
+	installations map[Key]map[string]int64
+	permissions   map[Key]map[int64]*github.InstallationPermissions
+	repositories  map[Key]map[int64]cachedRepositories
+	tokens        map[Key]map[string]*github.InstallationToken
+	minting       singleflight.Group
+	rateLimits    map[Key]*RateLimit
 }
 
 // Option configures a [Registry] at construction time.
@@ -83,6 +118,14 @@ func NewRegistry(operatorNS string, startupCfg *OperatorConfig, opts ...Option)
 		startupCfg: startupCfg,
 		operatorNS: operatorNS,
 		factory:    defaultFactory,
+		signer:     defaultSigner,
+
+		appsFactory:   defaultAppsFactory,
+		installations: make(map[Key]map[string]int64),
+		permissions:   make(map[Key]map[int64]*github.InstallationPermissions),
+		repositories:  make(map[Key]map[int64]cachedRepositories),
+		tokens:        make(map[Key]map[string]*github.InstallationToken),
+		rateLimits:    make(map[Key]*RateLimit),
 	}
 	for _, opt := range opts {
 		opt(r)
@@ -113,20 +156,21 @@ func (r *Registry) Startup(ctx context.Context) (ghait.GHAIT, error) {
 	if cached, ok := r.clients[StartupKey]; ok {
 		return cached.client, nil
 	}
-	client, err := r.factory(ctx, r.startupCfg)
+	cached, err := r.build(ctx, StartupKey, r.startupCfg)
 	if err != nil {
 		return nil, fmt.Errorf("startup GitHub App: %w", err)
 	}
-	r.clients[StartupKey] = cachedClient{client: client}
-	return client, nil
+	r.clients[StartupKey] = cached
+	return cached.client, nil
 }
 
 // ForApp returns a cached client for the given App, building it when the
 // cached entry's version differs from the supplied one. The version is an
 // opaque string the caller derives from all inputs that affect client
 // identity (typically the App's spec generation, plus any referenced
-// Secret's ResourceVersion when the App is Secret-backed).
-func (r *Registry) ForApp(ctx context.Context, key Key, version string, cfg ghait.Config) (ghait.GHAIT, error) {
+// Secret's ResourceVersion when the App is Secret-backed, plus its GitHub
+// Enterprise Server endpoint).
+func (r *Registry) ForApp(ctx context.Context, key Key, version string, cfg *OperatorConfig) (ghait.GHAIT, error) {
 	if key == StartupKey {
 		return nil, errors.New("ForApp called with reserved startup key; use Startup()")
 	}
@@ -141,12 +185,65 @@ func (r *Registry) ForApp(ctx context.Context, key Key, version string, cfg ghai
 	if cached, ok := r.clients[key]; ok && cached.version == version {
 		return cached.client, nil
 	}
-	client, err := r.factory(ctx, cfg)
+	cached, err := r.build(ctx, key, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("App %s/%s: %w", key.Namespace, key.Name, err)
+		return nil, fmt.Errorf("App %s: %w", key, err)
 	}
-	r.clients[key] = cachedClient{client: client, version: version}
-	return client, nil
+	cached.version = version
+	r.clients[key] = cached
+	delete(r.installations, key)
+	delete(r.permissions, key)
+	delete(r.repositories, key)
+	delete(r.tokens, key)
+	return cached.client, nil
+}
+
+// build constructs the clients for the App cached under key from a copy of
+// cfg, first setting the copy's HTTPClient from its CA bundle and proxy so
+// that every client built for the App shares them. cfg itself, which may be
+// the operator's long-lived startup config, is left unchanged. Its
+// App-authenticated requests, including mints, feed the App's [RateLimit];
+// requests authenticated with an installation token count against the
+// installation's own rate limit, so are not tracked. The App-authenticated
+// client, used both to mint tokens and for the Apps API, signs its JWTs
+// with the App's key provider and is passed explicitly to the factories.
+// With cfg.ValidateKey, the key is test-signed first. The caller must hold
+// r.mu for writing.
+func (r *Registry) build(ctx context.Context, key Key, cfg *OperatorConfig) (cachedClient, error) {
+	httpClient, err := NewHTTPClient(cfg.CABundle, cfg.ProxyURL)
+	if err != nil {
+		return cachedClient{}, err
+	}
+	gh, err := NewGitHubClient(httpClient, cfg.BaseURL, cfg.UploadURL)
+	if err != nil {
+		return cachedClient{}, err
+	}
+	base := http.DefaultTransport
+	if httpClient != nil {
+		base = httpClient.Transport
+	}
+	appCfg := *cfg
+	appCfg.HTTPClient = &http.Client{Transport: &rateLimitTransport{base: base, limit: r.rateLimit(key)}}
+	cfg = &appCfg
+	signer, err := r.signer(ctx, cfg)
+	if err != nil {
+		return cachedClient{}, fmt.Errorf("provider %q: %w", cfg.Provider, err)
+	}
+	if cfg.ValidateKey {
+		if err := validateSigner(signer, cfg.AppID); err != nil {
+			return cachedClient{}, fmt.Errorf("provider %q: %w", cfg.Provider, err)
+		}
+	}
+	app, err := NewAppGitHubClient(cfg, signer)
+	if err != nil {
+		return cachedClient{}, err
+	}
+	client, err := r.factory(ctx, cfg, app)
+	if err != nil {
+		return cachedClient{}, err
+	}
+	apps, appsErr := r.appsFactory(app)
+	return cachedClient{client: client, github: gh, apps: apps, appsErr: appsErr}, nil
 }
 
 // Lookup returns the cached client for key, if any. Unlike [Registry.ForApp]
@@ -156,18 +253,41 @@ func (r *Registry) ForApp(ctx context.Context, key Key, version string, cfg ghai
 // yet populated (or has invalidated) the entry; the caller should requeue
 // and let the App watch re-trigger.
 func (r *Registry) Lookup(key Key) (ghait.GHAIT, bool) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	cached, ok := r.clients[key]
+	cached, ok := r.lookupCached(key)
 	if !ok {
 		return nil, false
 	}
 	return cached.client, true
 }
 
+func (r *Registry) lookupCached(key Key) (cachedClient, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cached, ok := r.clients[key]
+	return cached, ok
+}
+
+// GitHubClient returns an unauthenticated go-github client for the endpoint,
+// CA bundle and proxy of the App cached under key, for API calls that
+// authenticate with an installation token rather than as the App. It falls
+// back to a github.com client when nothing is cached.
+func (r *Registry) GitHubClient(key Key) *github.Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if cached, ok := r.clients[key]; ok && cached.github != nil {
+		return cached.github
+	}
+	return github.NewClient(nil)
+}
+
 // Invalidate drops the cache entry for key. Safe to call for unknown keys.
 func (r *Registry) Invalidate(key Key) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	delete(r.clients, key)
+	delete(r.installations, key)
+	delete(r.permissions, key)
+	delete(r.repositories, key)
+	delete(r.tokens, key)
+	delete(r.rateLimits, key)
 }
@@ -27,7 +27,7 @@ func (f *fakeGHAIT) NewTokenWithOptions(context.Context, *github.InstallationTok
 
 func countingFactory() (FactoryFunc, *int) {
 	var calls int
-	return func(ctx context.Context, cfg ghait.Config) (ghait.GHAIT, error) {
+	return func(ctx context.Context, cfg *OperatorConfig, _ *github.Client) (ghait.GHAIT, error) {
 		calls++
 		return &fakeGHAIT{id: cfg.GetAppID()}, nil
 	}, &calls
@@ -42,7 +42,7 @@ func TestRegistry_Startup_NoConfig(t *testing.T) {
 }
 
 func TestRegistry_Startup_CachesAcrossCalls(t *testing.T) {
-	cfg := &OperatorConfig{AppID: 1, InstallationID: 2, Provider: "file", Key: "inline"}
+	cfg := &OperatorConfig{AppID: 1, InstallationID: 2, Provider: "file", Key: testAppKey(t)}
 	fac, calls := countingFactory()
 	r := NewRegistry("gtm-system", cfg, WithFactory(fac))
 
@@ -67,7 +67,7 @@ func TestRegistry_ForApp_CachesByVersion(t *testing.T) {
 	r := NewRegistry("gtm-system", nil, WithFactory(fac))
 
 	key := Key{Namespace: "team-a", Name: "prod"}
-	cfg := &OperatorConfig{AppID: 42, InstallationID: 7, Provider: "file", Key: "inline"}
+	cfg := &OperatorConfig{AppID: 42, InstallationID: 7, Provider: "file", Key: testAppKey(t)}
 	ctx := context.Background()
 
 	c1, err := r.ForApp(ctx, key, "1", cfg)
@@ -107,7 +107,7 @@ func TestRegistry_Invalidate_EvictsEntry(t *testing.T) {
 	r := NewRegistry("gtm-system", nil, WithFactory(fac))
 
 	key := Key{Namespace: "team-a", Name: "prod"}
-	cfg := &OperatorConfig{AppID: 42, Provider: "file", Key: "inline"}
+	cfg := &OperatorConfig{AppID: 42, Provider: "file", Key: testAppKey(t)}
 	ctx := context.Background()
 
 	if _, err := r.ForApp(ctx, key, "5", cfg); err != nil {
@@ -125,8 +125,8 @@ func TestRegistry_Invalidate_EvictsEntry(t *testing.T) {
 func TestRegistry_FactoryError_Propagates(t *testing.T) {
 	sentinel := errors.New("provider init failed")
 	r := NewRegistry("gtm-system",
-		&OperatorConfig{AppID: 1, Provider: "file", Key: "inline"},
-		WithFactory(func(context.Context, ghait.Config) (ghait.GHAIT, error) {
+		&OperatorConfig{AppID: 1, Provider: "file", Key: testAppKey(t)},
+		WithFactory(func(context.Context, *OperatorConfig, *github.Client) (ghait.GHAIT, error) {
 			return nil, sentinel
 		}),
 	)
@@ -142,3 +142,56 @@ func TestRegistry_OperatorNamespace(t *testing.T) {
 		t.Errorf("OperatorNamespace() = %q, want my-ns", got)
 	}
 }
+
+func TestRegistry_ForApp_BuildsTransport(t *testing.T) {
+	var got *OperatorConfig
+	r := NewRegistry("gtm-system", nil, WithFactory(func(_ context.Context, cfg *OperatorConfig, _ *github.Client) (ghait.GHAIT, error) {
+		got = cfg
+		return &fakeGHAIT{id: cfg.GetAppID()}, nil
+	}))
+	ctx := context.Background()
+
+	key := Key{Namespace: "team-a", Name: "ghes"}
+	cfg := &OperatorConfig{
+		AppID:    42,
+		Provider: "file",
+		Key:      testAppKey(t),
+		BaseURL:  "https://github.example.com",
+		ProxyURL: "http://proxy.example.com:3128",
+	}
+	if _, err := r.ForApp(ctx, key, "1", cfg); err != nil {
+		t.Fatalf("ForApp() err = %v", err)
+	}
+	if got.HTTPClient == nil {
+		t.Error("factory config has no HTTP client")
+	}
+	if cfg.HTTPClient != nil {
+		t.Error("ForApp() set HTTPClient on the caller's config")
+	}
+	if base := r.GitHubClient(key).BaseURL.String(); base != "https://github.example.com/api/v3/" {
+		t.Errorf("GitHubClient().BaseURL = %q, want GHES API URL", base)
+	}
+	if base := r.GitHubClient(Key{Name: "unknown"}).BaseURL.String(); base != "https://api.github.com/" {
+		t.Errorf("GitHubClient(unknown).BaseURL = %q, want github.com", base)
+	}
+
+	bad := Key{Namespace: "team-a", Name: "bad-ca"}
+	if _, err := r.ForApp(ctx, bad, "1", &OperatorConfig{AppID: 43, CABundle: "not a certificate"}); err == nil {
+		t.Error("ForApp() with invalid CA bundle returned no error")
+	}
+	if _, ok := r.Lookup(bad); ok {
+		t.Error("ForApp() cached a client despite an invalid CA bundle")
+	}
+}
+
+func TestKey_String(t *testing.T) {
+	for key, want := range map[Key]string{
+		StartupKey:                            "startup",
+		{Name: "cluster-app"}:                 "cluster-app",
+		{Namespace: "team-a", Name: "tenant"}: "team-a/tenant",
+	} {
+		if got := key.String(); got != want {
+			t.Errorf("%#v.String() = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package ghapp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// repositoriesCacheTTL is how long the repositories listed for an
+// installation are reused before being listed afresh.
+const repositoriesCacheTTL = 5 * time.Minute
+
+// Repository describes a repository accessible to an installation, with the
+// attributes a repository selector matches on.
+type Repository struct {
+	// Name of the repository, without its owner.
+	Name string
+	// Owner is the login of the account owning the repository.
+	Owner string
+	// Topics carried by the repository.
+	Topics []string
+	// CustomProperties holds the values of the repository's custom
+	// properties, if they were requested; a single-select or text property
+	// has exactly one value.
+	CustomProperties map[string][]string
+}
+
+type cachedRepositories struct {
+	repositories     []Repository
+	customProperties bool
+	listedAt         time.Time
+}
+
+// InstallationRepositories lists the repositories accessible to an
+// installation of the App cached under key, with their custom property
+// values when customProperties is set. Listing authenticates as the
+// installation, with a short-lived token limited to reading metadata (and
+// custom properties), which is revoked afterwards. Results are cached for a
+// few minutes, or until the App's client is rebuilt or invalidated.
+func (r *Registry) InstallationRepositories(ctx context.Context, key Key, installationID int64, customProperties bool) ([]Repository, error) {
+	r.mu.RLock()
+	cached, ok := r.clients[key]
+	listed, known := r.repositories[key][installationID]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("App %s: client not yet cached", key)
+	}
+	if known && (listed.customProperties || !customProperties) && time.Since(listed.listedAt) < repositoriesCacheTTL {
+		return listed.repositories, nil
+	}
+
+	permissions := &github.InstallationPermissions{Metadata: github.Ptr("read")}
+	if customProperties {
+		permissions.OrganizationCustomProperties = github.Ptr("read")
+	}
+	token, err := cached.client.NewInstallationToken(ctx, installationID, &github.InstallationTokenOptions{Permissions: permissions})
+	if err != nil {
+		return nil, fmt.Errorf("mint token to list repositories: %w", err)
+	}
+	gh := cached.github.WithAuthToken(token.GetToken())
+	defer func() {
+		// Best effort: the token expires on its own within the hour.
+		_, _ = gh.Apps.RevokeInstallationToken(context.WithoutCancel(ctx))
+	}()
+
+	repositories, err := listInstallationRepositories(ctx, gh)
+	if err != nil {
+		return nil, err
+	}
+	if customProperties {
+		if err := addCustomProperties(ctx, gh, repositories); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current, ok := r.clients[key]; ok && current.client == cached.client {
+		if r.repositories[key] == nil {
+			r.repositories[key] = make(map[int64]cachedRepositories)
+		}
+		r.repositories[key][installationID] = cachedRepositories{
+			repositories:     repositories,
+			customProperties: customProperties,
+			listedAt:         time.Now(),
+		}
+	}
+	return repositories, nil
+}
+
+// listInstallationRepositories lists every repository accessible to the
+// installation gh authenticates as.
+func listInstallationRepositories(ctx context.Context, gh *github.Client) ([]Repository, error) {
+	var repositories []Repository
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := gh.Apps.ListRepos(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("list installation repositories: %w", err)
+		}
+		for _, repo := range page.Repositories {
+			repositories = append(repositories, Repository{
+				Name:   repo.GetName(),
+				Owner:  repo.GetOwner().GetLogin(),
+				Topics: repo.Topics,
+			})
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return repositories, nil
+}
+
+// addCustomProperties sets the custom property values of each repository
+// from those listed for its owning organization. Repositories owned by a
+// user have no custom properties.
+func addCustomProperties(ctx context.Context, gh *github.Client, repositories []Repository) error {
+	values := make(map[string]map[string][]string)
+	listed := make(map[string]bool)
+	for _, repo := range repositories {
+		if listed[repo.Owner] {
+			continue
+		}
+		listed[repo.Owner] = true
+		opts := &github.ListCustomPropertyValuesOptions{ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			page, resp, err := gh.Organizations.ListCustomPropertyValues(ctx, repo.Owner, opts)
+			if isNotFound(resp) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("list custom property values for %s: %w", repo.Owner, err)
+			}
+			for _, entry := range page {
+				values[entry.RepositoryFullName] = customPropertyValues(entry.Properties)
+			}
+			if resp == nil || resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+	for i := range repositories {
+		repositories[i].CustomProperties = values[repositories[i].Owner+"/"+repositories[i].Name]
+	}
+	return nil
+}
+
+// customPropertyValues returns the values of properties by name. A
+// multi-select property may hold several values.
+func customPropertyValues(properties []*github.CustomPropertyValue) map[string][]string {
+	values := make(map[string][]string, len(properties))
+	for _, property := range properties {
+		switch value := property.Value.(type) {
+		case string:
+			values[property.PropertyName] = []string{value}
+		case []string:
+			values[property.PropertyName] = value
+		}
+	}
+	return values
+}
@@ -0,0 +1,90 @@
+package ghapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// repositoriesHandler serves the endpoints used to list the repositories of
+// installation 7 of a GitHub Enterprise Server App, recording each request.
+type repositoriesHandler struct {
+	paths []string
+}
+
+func (h *repositoriesHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.paths = append(h.paths, req.Method+" "+req.URL.Path)
+	w.Header().Set("Content-Type", "application/json")
+	switch req.Method + " " + req.URL.Path {
+	case "POST /api/v3/app/installations/7/access_tokens":
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"token":"ghs_list","expires_at":"2030-01-01T00:00:00Z"}`))
+	case "GET /api/v3/installation/repositories":
+		_, _ = w.Write([]byte(`{"total_count":2,"repositories":[
+			{"name":"service-a","owner":{"login":"acme"},"topics":["go"]},
+			{"name":"docs","owner":{"login":"acme"}}
+		]}`))
+	case "GET /api/v3/orgs/acme/properties/values":
+		_, _ = w.Write([]byte(`[
+			{"repository_full_name":"acme/service-a","properties":[{"property_name":"environment","value":"production"}]},
+			{"repository_full_name":"acme/docs","properties":[{"property_name":"regions","value":["eu","us"]}]}
+		]`))
+	case "DELETE /api/v3/installation/token":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	}
+}
+
+func TestRegistry_InstallationRepositories(t *testing.T) {
+	h := &repositoriesHandler{}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	r := NewRegistry("gtm-system", nil)
+	ctx := context.Background()
+	key := Key{Namespace: "team-a", Name: "ghes"}
+	cfg := &OperatorConfig{AppID: 42, InstallationID: 7, Provider: "file", Key: testAppKey(t), BaseURL: srv.URL + "/api/v3/"}
+	if _, err := r.ForApp(ctx, key, "1", cfg); err != nil {
+		t.Fatalf("ForApp() err = %v", err)
+	}
+
+	repositories, err := r.InstallationRepositories(ctx, key, 7, true)
+	if err != nil {
+		t.Fatalf("InstallationRepositories() err = %v", err)
+	}
+	if len(repositories) != 2 {
+		t.Fatalf("InstallationRepositories() = %v, want 2 repositories", repositories)
+	}
+	service := repositories[0]
+	if service.Name != "service-a" || service.Owner != "acme" || !slices.Equal(service.Topics, []string{"go"}) {
+		t.Errorf("repositories[0] = %+v, want acme/service-a with topic go", service)
+	}
+	if got := service.CustomProperties["environment"]; !slices.Equal(got, []string{"production"}) {
+		t.Errorf("service-a environment = %v, want [production]", got)
+	}
+	if got := repositories[1].CustomProperties["regions"]; !slices.Equal(got, []string{"eu", "us"}) {
+		t.Errorf("docs regions = %v, want [eu us]", got)
+	}
+	if !slices.Contains(h.paths, "DELETE /api/v3/installation/token") {
+		t.Errorf("requests = %v, want the listing token revoked", h.paths)
+	}
+
+	// Cached, including for a listing without custom properties.
+	requests := len(h.paths)
+	if _, err := r.InstallationRepositories(ctx, key, 7, false); err != nil {
+		t.Fatalf("InstallationRepositories() 2nd call err = %v", err)
+	}
+	if len(h.paths) != requests {
+		t.Errorf("requests after cached call = %v, want none", h.paths[requests:])
+	}
+
+	// Invalidation drops the cached listing.
+	r.Invalidate(key)
+	if _, err := r.InstallationRepositories(ctx, key, 7, false); err == nil {
+		t.Error("InstallationRepositories() after Invalidate err = nil, want client not yet cached")
+	}
+}
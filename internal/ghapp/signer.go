@@ -0,0 +1,73 @@
+package ghapp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/isometry/ghait/v84/provider"
+)
+
+// SignerFunc returns the signer of the JWTs that authenticate requests as
+// the App with cfg's key. It is a variable on the [Registry] so tests can
+// inject a fake in place of a KMS provider.
+type SignerFunc func(ctx context.Context, cfg *OperatorConfig) (ghinstallation.Signer, error)
+
+// defaultSigner signs with the key itself for the file provider, and
+// otherwise through the KMS provider registered with ghait under
+// cfg.Provider, so that the key never leaves the KMS.
+var defaultSigner SignerFunc = func(ctx context.Context, cfg *OperatorConfig) (ghinstallation.Signer, error) {
+	if cfg.Provider != "file" {
+		return provider.NewSigner(ctx, cfg.Provider, cfg.Key)
+	}
+	data, err := readAppKey(cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	return ghinstallation.NewRSASigner(jwt.SigningMethodRS256, key), nil
+}
+
+// WithSigner replaces the default JWT signer factory. Intended for tests.
+func WithSigner(f SignerFunc) Option {
+	return func(r *Registry) {
+		r.signer = f
+	}
+}
+
+// readAppKey returns a PEM-encoded private key, given inline or as the path
+// of a file holding it.
+func readAppKey(key string) ([]byte, error) {
+	if strings.Contains(key, "-----BEGIN") {
+		return []byte(key), nil
+	}
+	data, err := os.ReadFile(key)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	return data, nil
+}
+
+// validateSigner signs a JWT that is never sent, so that a key that is
+// missing, malformed or not usable by the operator fails when the App's
+// client is built rather than at its first mint.
+func validateSigner(signer ghinstallation.Signer, appID int64) error {
+	now := time.Now()
+	_, err := signer.Sign(&jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		Issuer:    strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return fmt.Errorf("validate key: %w", err)
+	}
+	return nil
+}
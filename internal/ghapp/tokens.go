@@ -0,0 +1,93 @@
+package ghapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// MintFunc mints a new installation token.
+type MintFunc func(ctx context.Context) (*github.InstallationToken, error)
+
+// SharedTokens shares the installation tokens of one App between owners that
+// request identical scope, so that they are minted once rather than once per
+// owner.
+type SharedTokens struct {
+	registry *Registry
+	key      Key
+}
+
+// SharedTokens returns the installation tokens shared between owners of the
+// App cached under key.
+func (r *Registry) SharedTokens(key Key) *SharedTokens {
+	return &SharedTokens{registry: r, key: key}
+}
+
+// Token returns the token last minted for installationID with options,
+// reporting a hit, while fresh reports its expiry as still fresh for the
+// caller. Otherwise it mints a new token with mint and caches it for the
+// next caller; concurrent misses for the same scope share a single mint.
+// Tokens are cached until the App's client is rebuilt or invalidated.
+func (t *SharedTokens) Token(ctx context.Context, installationID int64, options *github.InstallationTokenOptions, fresh func(expiresAt time.Time) bool, mint MintFunc) (token *github.InstallationToken, hit bool, err error) {
+	r := t.registry
+	scope := strconv.FormatInt(installationID, 10) + "/" + tokenScope(options)
+
+	r.mu.RLock()
+	cached, ok := r.clients[t.key]
+	shared, known := r.tokens[t.key][scope]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, false, fmt.Errorf("App %s: client not yet cached", t.key)
+	}
+	if known && fresh(shared.GetExpiresAt().Time) {
+		return shared, true, nil
+	}
+
+	minted, err, _ := r.minting.Do(t.key.String()+"/"+scope, func() (any, error) {
+		return mint(ctx)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	token = minted.(*github.InstallationToken)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current, ok := r.clients[t.key]; ok && current.client == cached.client {
+		tokens := r.tokens[t.key]
+		if tokens == nil {
+			tokens = make(map[string]*github.InstallationToken)
+			r.tokens[t.key] = tokens
+		}
+		for scope, shared := range tokens {
+			if time.Now().After(shared.GetExpiresAt().Time) {
+				delete(tokens, scope)
+			}
+		}
+		if shared, ok := tokens[scope]; !ok || token.GetExpiresAt().After(shared.GetExpiresAt().Time) {
+			tokens[scope] = token
+		}
+	}
+	return token, false, nil
+}
+
+// tokenScope returns a digest of the permissions and repositories requested
+// by options, independent of the order in which repositories are listed.
+func tokenScope(options *github.InstallationTokenOptions) string {
+	var scope github.InstallationTokenOptions
+	if options != nil {
+		scope.Permissions = options.Permissions
+		scope.Repositories = slices.Sorted(slices.Values(options.Repositories))
+		scope.RepositoryIDs = slices.Sorted(slices.Values(options.RepositoryIDs))
+	}
+	encoded, _ := json.Marshal(scope)
+	digest := sha256.Sum256(encoded)
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,66 @@
+package ghapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+func TestSharedTokens_Token(t *testing.T) {
+	fac, _ := countingFactory()
+	r := NewRegistry("gtm-system", nil, WithFactory(fac))
+	ctx := context.Background()
+	key := Key{Namespace: "team-a", Name: "prod"}
+	if _, err := r.ForApp(ctx, key, "1", &OperatorConfig{AppID: 42, InstallationID: 7, Provider: "file", Key: testAppKey(t)}); err != nil {
+		t.Fatalf("ForApp() err = %v", err)
+	}
+
+	var mints int
+	mint := func(context.Context) (*github.InstallationToken, error) {
+		mints++
+		return &github.InstallationToken{
+			Token:     github.Ptr("ghs_shared"),
+			ExpiresAt: &github.Timestamp{Time: time.Now().Add(time.Hour)},
+		}, nil
+	}
+	fresh := func(time.Time) bool { return true }
+	due := func(time.Time) bool { return false }
+	options := func(repositories ...string) *github.InstallationTokenOptions {
+		return &github.InstallationTokenOptions{
+			Permissions:  &github.InstallationPermissions{Contents: github.Ptr("read")},
+			Repositories: repositories,
+		}
+	}
+	token := func(installationID int64, opts *github.InstallationTokenOptions, fresh func(time.Time) bool) bool {
+		t.Helper()
+		got, hit, err := r.SharedTokens(key).Token(ctx, installationID, opts, fresh, mint)
+		if err != nil || got.GetToken() != "ghs_shared" {
+			t.Fatalf("Token() = %v, %v, want ghs_shared", got, err)
+		}
+		return hit
+	}
+
+	if token(7, options("a", "b"), fresh) {
+		t.Error("first Token() hit, want miss")
+	}
+	if !token(7, options("b", "a"), fresh) || mints != 1 {
+		t.Errorf("Token() for the same scope in another order: mints = %d, want a hit", mints)
+	}
+	if token(7, options("a"), fresh) || mints != 2 {
+		t.Errorf("Token() for other repositories: mints = %d, want a miss", mints)
+	}
+	if token(8, options("a", "b"), fresh) || mints != 3 {
+		t.Errorf("Token() for another installation: mints = %d, want a miss", mints)
+	}
+	if token(7, options("a", "b"), due) || mints != 4 {
+		t.Errorf("Token() once due for refresh: mints = %d, want a miss", mints)
+	}
+
+	// Invalidation drops the shared tokens along with the client.
+	r.Invalidate(key)
+	if _, _, err := r.SharedTokens(key).Token(ctx, 7, options("a", "b"), fresh, mint); err == nil {
+		t.Error("Token() after Invalidate err = nil, want client not yet cached")
+	}
+}
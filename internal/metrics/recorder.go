@@ -26,6 +26,16 @@ const (
 	ReasonSecretCreate = "secret_create"
 	ReasonSecretUpdate = "secret_update"
 	ReasonStatusUpdate = "status_update"
+	ReasonTemplate     = "template"
+
+	ReasonInstallationNotFound = "installation_not_found"
+	ReasonInvalidRepositories  = "invalid_repositories"
+	ReasonPermissionsRejected  = "permissions_rejected"
+	ReasonRateLimited          = "rate_limited"
+	ReasonAuthenticationFailed = "authentication_failed"
+
+	CacheHit  = "hit"
+	CacheMiss = "miss"
 )
 
 // Recorder holds all custom OTEL metric instruments for the operator.
@@ -42,6 +52,11 @@ type Recorder struct {
 	tokensActive         metric.Int64UpDownCounter
 	secretOperations     metric.Int64Counter
 	configErrors         metric.Int64Counter
+	tokenRevocations     metric.Int64Counter
+	mintsSkipped         metric.Int64Counter
+	tokenCacheLookups    metric.Int64Counter
+	rateLimitRemaining   metric.Float64Gauge
+	rateLimitReset       metric.Float64Gauge
 
 	activeTokens sync.Map
 }
@@ -122,6 +137,41 @@ func newRecorder(meter metric.Meter) (*Recorder, error) {
 		return nil, err
 	}
 
+	if r.tokenRevocations, err = meter.Int64Counter("token.revocations",
+		metric.WithUnit("{revocation}"),
+		metric.WithDescription("Total number of installation token revocations"),
+	); err != nil {
+		return nil, err
+	}
+
+	if r.mintsSkipped, err = meter.Int64Counter("token.mints.skipped",
+		metric.WithUnit("{mint}"),
+		metric.WithDescription("Total number of reconciles that kept a still-valid token instead of minting"),
+	); err != nil {
+		return nil, err
+	}
+
+	if r.tokenCacheLookups, err = meter.Int64Counter("token.cache.lookups",
+		metric.WithUnit("{lookup}"),
+		metric.WithDescription("Total number of shared installation token lookups (by result)"),
+	); err != nil {
+		return nil, err
+	}
+
+	if r.rateLimitRemaining, err = meter.Float64Gauge("github.ratelimit.remaining",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("GitHub API requests remaining in the GitHub App's rate limit window"),
+	); err != nil {
+		return nil, err
+	}
+
+	if r.rateLimitReset, err = meter.Float64Gauge("github.ratelimit.reset.timestamp",
+		metric.WithUnit("s"),
+		metric.WithDescription("Unix timestamp when the GitHub App's rate limit window resets"),
+	); err != nil {
+		return nil, err
+	}
+
 	return &r, nil
 }
 
@@ -256,3 +306,54 @@ func (r *Recorder) RecordConfigError(ctx context.Context, controllerName, source
 		),
 	)
 }
+
+// RecordTokenRevocation records an attempt to revoke an installation token.
+func (r *Recorder) RecordTokenRevocation(ctx context.Context, controllerName, result string) {
+	if r == nil {
+		return
+	}
+	r.tokenRevocations.Add(ctx, 1,
+		metric.WithAttributes(
+			attribute.String("controller", controllerName),
+			attribute.String("result", result),
+		),
+	)
+}
+
+// RecordMintSkipped records a reconcile that kept the still-valid token held
+// by the managed Secret instead of minting a new one.
+func (r *Recorder) RecordMintSkipped(ctx context.Context, controllerName string) {
+	if r == nil {
+		return
+	}
+	r.mintsSkipped.Add(ctx, 1,
+		metric.WithAttributes(
+			attribute.String("controller", controllerName),
+		),
+	)
+}
+
+// RecordTokenCacheLookup records a lookup of a shared installation token,
+// with result [CacheHit] or [CacheMiss].
+func (r *Recorder) RecordTokenCacheLookup(ctx context.Context, controllerName, result string) {
+	if r == nil {
+		return
+	}
+	r.tokenCacheLookups.Add(ctx, 1,
+		metric.WithAttributes(
+			attribute.String("controller", controllerName),
+			attribute.String("result", result),
+		),
+	)
+}
+
+// RecordRateLimit records the GitHub API rate limit budget last reported for
+// a GitHub App: the requests remaining and when the window resets.
+func (r *Recorder) RecordRateLimit(ctx context.Context, app string, remaining int, reset time.Time) {
+	if r == nil {
+		return
+	}
+	attrs := metric.WithAttributes(attribute.String("app", app))
+	r.rateLimitRemaining.Record(ctx, float64(remaining), attrs)
+	r.rateLimitReset.Record(ctx, float64(reset.Unix()), attrs)
+}
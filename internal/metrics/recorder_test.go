@@ -26,6 +26,10 @@ func TestNilRecorderSafety(t *testing.T) {
 	r.RemoveTokenActive(ctx, "github-token", "default/my-token")
 	r.RecordSecretOperation(ctx, "github-token", OperationCreate, ResultSuccess)
 	r.RecordConfigError(ctx, "github-token", "file")
+	r.RecordTokenRevocation(ctx, "github-token", ResultSuccess)
+	r.RecordMintSkipped(ctx, "github-token")
+	r.RecordTokenCacheLookup(ctx, "github-token", CacheHit)
+	r.RecordRateLimit(ctx, "default/my-app", 4999, time.Now())
 	if err := r.Shutdown(ctx); err != nil {
 		t.Errorf("Shutdown on nil receiver returned error: %v", err)
 	}
@@ -55,6 +59,12 @@ func TestRecorderInstruments(t *testing.T) {
 	r.EnsureTokenActive(ctx, "github-token", "default/my-token")
 	r.RecordSecretOperation(ctx, "github-token", OperationCreate, ResultSuccess)
 	r.RecordConfigError(ctx, "github-app", "app")
+	r.RecordTokenRevocation(ctx, "github-token", ResultError)
+	r.RecordMintSkipped(ctx, "github-token")
+	r.RecordTokenCacheLookup(ctx, "github-token", CacheHit)
+	r.RecordTokenCacheLookup(ctx, "github-token", CacheMiss)
+	r.RecordTokenCacheLookup(ctx, "github-token", CacheHit)
+	r.RecordRateLimit(ctx, "default/my-app", 4321, time.Unix(1700003600, 0))
 
 	// Collect and verify.
 	var rm metricdata.ResourceMetrics
@@ -98,6 +108,26 @@ func TestRecorderInstruments(t *testing.T) {
 		1,
 	)
 
+	// Verify token revocations counter.
+	assertCounterValue(t, metrics, "token.revocations",
+		attribute.String("controller", "github-token"),
+		attribute.String("result", ResultError),
+		1,
+	)
+
+	// Verify skipped mints counter.
+	assertCounterValue(t, metrics, "token.mints.skipped",
+		attribute.String("controller", "github-token"),
+		1,
+	)
+
+	// Verify token cache lookups counter.
+	assertCounterValue(t, metrics, "token.cache.lookups",
+		attribute.String("controller", "github-token"),
+		attribute.String("result", CacheHit),
+		2,
+	)
+
 	// Verify tokens active up-down counter.
 	assertCounterValue(t, metrics, "tokens.active",
 		attribute.String("controller", "github-token"),
@@ -122,6 +152,8 @@ func TestRecorderInstruments(t *testing.T) {
 
 	// Verify gauge value.
 	assertGaugeValue(t, metrics, "token.expiry.timestamp", 1700000000)
+	assertGaugeValue(t, metrics, "github.ratelimit.remaining", 4321)
+	assertGaugeValue(t, metrics, "github.ratelimit.reset.timestamp", 1700003600)
 }
 
 func TestActiveTokenIdempotency(t *testing.T) {
@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/ghapp"
+	tm "github.com/isometry/github-token-manager/internal/tokenmanager"
+)
+
+// CheckInstallation returns a description of the permissions owner requests
+// beyond those granted to the installation its tokens are minted for, or ""
+// if there are none. The check is skipped, returning "", when the App's
+// client is not cached under key. If the installation cannot be looked up
+// the check fails, and GitHub then rejects any excess only when the token
+// is minted.
+func CheckInstallation(ctx context.Context, reg *ghapp.Registry, key ghapp.Key, owner tm.TokenManager) (string, error) {
+	options := owner.GetInstallationTokenOptions()
+	if options == nil || options.Permissions == nil {
+		return "", nil
+	}
+	client, ok := reg.Lookup(key)
+	if !ok {
+		return "", nil
+	}
+
+	installationID := owner.GetInstallationID()
+	if selector := owner.GetInstallationSelector(); selector != nil {
+		id, err := reg.Installation(ctx, key, selector.String())
+		if err != nil {
+			return "", err
+		}
+		installationID = id
+	}
+	if installationID == 0 {
+		installationID = client.GetInstallationID()
+	}
+
+	granted, err := reg.InstallationPermissions(ctx, key, installationID)
+	if err != nil {
+		return "", err
+	}
+	exceeded := PermissionsExceeding(options.Permissions, granted)
+	if len(exceeded) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("requested permissions exceed those granted to installation %d: %s",
+		installationID, strings.Join(exceeded, ", ")), nil
+}
+
+// AppKey returns the registry key of the App or ClusterApp that ref resolves
+// to, or [ghapp.StartupKey] for a nil ref. An empty namespace on an App
+// reference is resolved against operatorNamespace.
+func AppKey(ref *githubv1.AppReference, operatorNamespace string) ghapp.Key {
+	switch {
+	case ref == nil:
+		return ghapp.StartupKey
+	case ref.IsClusterApp():
+		return ghapp.Key{Name: ref.Name}
+	case ref.Namespace == "":
+		return ghapp.Key{Namespace: operatorNamespace, Name: ref.Name}
+	default:
+		return ghapp.Key{Namespace: ref.Namespace, Name: ref.Name}
+	}
+}
+
+// AppGranted reports whether any AppGrant in the App's namespace permits
+// Tokens in namespace from to reference it.
+func AppGranted(ctx context.Context, c client.Reader, app types.NamespacedName, from string) (bool, error) {
+	var grants githubv1.AppGrantList
+	if err := c.List(ctx, &grants, client.InNamespace(app.Namespace)); err != nil {
+		return false, err
+	}
+	if len(grants.Items) == 0 {
+		return false, nil
+	}
+
+	var namespace corev1.Namespace
+	if err := c.Get(ctx, client.ObjectKey{Name: from}, &namespace); err != nil {
+		return false, err
+	}
+	for i := range grants.Items {
+		granted, err := grants.Items[i].Grants(app.Name, &namespace)
+		if err != nil {
+			return false, fmt.Errorf("AppGrant %s: %w", grants.Items[i].Name, err)
+		}
+		if granted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-github/v84/github"
+	"github.com/isometry/ghait/v84"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/ghapp"
+)
+
+type fakeGHAIT struct{}
+
+func (fakeGHAIT) GetAppID() int64          { return 42 }
+func (fakeGHAIT) GetInstallationID() int64 { return 7 }
+func (fakeGHAIT) NewInstallationToken(context.Context, int64, *github.InstallationTokenOptions) (*github.InstallationToken, error) {
+	return nil, nil
+}
+func (fakeGHAIT) NewToken(context.Context) (*github.InstallationToken, error) { return nil, nil }
+func (fakeGHAIT) NewTokenWithOptions(context.Context, *github.InstallationTokenOptions) (*github.InstallationToken, error) {
+	return nil, nil
+}
+
+// fakeSigner stands in for the App's key provider.
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(jwt.Claims) (string, error) { return "signed", nil }
+
+func withFakeSigner() ghapp.Option {
+	return ghapp.WithSigner(func(context.Context, *ghapp.OperatorConfig) (ghinstallation.Signer, error) {
+		return fakeSigner{}, nil
+	})
+}
+
+// fakeApps grants contents=read and metadata=read to installation 7.
+type fakeApps struct {
+	ghapp.AppsAPI
+}
+
+func (fakeApps) GetInstallation(_ context.Context, id int64) (*github.Installation, *github.Response, error) {
+	return &github.Installation{
+		ID: github.Ptr(id),
+		Permissions: &github.InstallationPermissions{
+			Contents: github.Ptr("read"),
+			Metadata: github.Ptr("read"),
+		},
+	}, &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}, nil
+}
+
+func TestCheckInstallation(t *testing.T) {
+	ctx := context.Background()
+	newRegistry := func(opts ...ghapp.Option) *ghapp.Registry {
+		opts = append([]ghapp.Option{withFakeSigner(), ghapp.WithFactory(func(context.Context, *ghapp.OperatorConfig, *github.Client) (ghait.GHAIT, error) {
+			return fakeGHAIT{}, nil
+		})}, opts...)
+		reg := ghapp.NewRegistry("gtm-system", nil, opts...)
+		if _, err := reg.ForApp(ctx, ghapp.Key{Namespace: "team-a", Name: "app"}, "1", &ghapp.OperatorConfig{AppID: 42}); err != nil {
+			t.Fatalf("ForApp() err = %v", err)
+		}
+		return reg
+	}
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ci"},
+		Spec: githubv1.TokenSpec{
+			AppRef: &githubv1.LocalAppReference{Name: "app"},
+			Permissions: &githubv1.Permissions{
+				Contents: github.Ptr("write"),
+				Metadata: github.Ptr("read"),
+			},
+		},
+	}
+	key := ghapp.Key{Namespace: "team-a", Name: "app"}
+
+	reg := newRegistry(ghapp.WithAppsFactory(func(*github.Client) (ghapp.AppsAPI, error) { return fakeApps{}, nil }))
+	got, err := CheckInstallation(ctx, reg, key, token)
+	want := "requested permissions exceed those granted to installation 7: contents=write"
+	if err != nil || got != want {
+		t.Errorf("CheckInstallation() = %q, %v; want %q", got, err, want)
+	}
+
+	token.Spec.Permissions.Contents = github.Ptr("read")
+	if got, err := CheckInstallation(ctx, reg, key, token); err != nil || got != "" {
+		t.Errorf("CheckInstallation() within grant = %q, %v; want none", got, err)
+	}
+
+	// An installation that cannot be looked up fails the check.
+	token.Spec.Permissions.Contents = github.Ptr("write")
+	errApps := errors.New("apps unavailable")
+	reg = newRegistry(ghapp.WithAppsFactory(func(*github.Client) (ghapp.AppsAPI, error) { return nil, errApps }))
+	if got, err := CheckInstallation(ctx, reg, key, token); !errors.Is(err, errApps) || got != "" {
+		t.Errorf("CheckInstallation() without Apps API = %q, %v; want %v", got, err, errApps)
+	}
+}
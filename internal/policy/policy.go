@@ -0,0 +1,185 @@
+// Package policy evaluates TokenPolicy and ClusterTokenPolicy resources
+// against the Tokens they govern.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	tm "github.com/isometry/github-token-manager/internal/tokenmanager"
+)
+
+// permissionLevels orders the access levels GitHub grants per permission.
+var permissionLevels = map[string]int{
+	"read":  1,
+	"write": 2,
+	"admin": 3,
+}
+
+// Evaluate returns a description of every way owner breaches the policies
+// that apply to it. Only namespaced Tokens are subject to policy: writing
+// ClusterTokens is reserved to cluster administrators.
+func Evaluate(ctx context.Context, c client.Reader, owner tm.TokenManager) ([]string, error) {
+	namespace := owner.GetNamespace()
+	if namespace == "" {
+		return nil, nil
+	}
+
+	var violations []string
+
+	var policies githubv1.TokenPolicyList
+	if err := c.List(ctx, &policies, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		for _, violation := range Check(&policy.Spec.TokenPolicyRules, owner) {
+			violations = append(violations, fmt.Sprintf("TokenPolicy %s: %s", policy.Name, violation))
+		}
+	}
+
+	var clusterPolicies githubv1.ClusterTokenPolicyList
+	if err := c.List(ctx, &clusterPolicies); err != nil {
+		return nil, err
+	}
+	if len(clusterPolicies.Items) == 0 {
+		return violations, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return nil, err
+	}
+	for i := range clusterPolicies.Items {
+		policy := &clusterPolicies.Items[i]
+		selected, err := selects(policy.Spec.NamespaceSelector, ns)
+		if err != nil {
+			return nil, fmt.Errorf("ClusterTokenPolicy %s: %w", policy.Name, err)
+		}
+		if !selected {
+			continue
+		}
+		for _, violation := range Check(&policy.Spec.TokenPolicyRules, owner) {
+			violations = append(violations, fmt.Sprintf("ClusterTokenPolicy %s: %s", policy.Name, violation))
+		}
+	}
+
+	return violations, nil
+}
+
+// selects reports whether selector matches ns; a nil selector matches every
+// namespace.
+func selects(selector *metav1.LabelSelector, ns *corev1.Namespace) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(ns.Labels)), nil
+}
+
+// Check returns a description of every way owner breaches rules.
+func Check(rules *githubv1.TokenPolicyRules, owner tm.TokenManager) []string {
+	var violations []string
+	options := owner.GetInstallationTokenOptions()
+
+	if rules.Permissions != nil {
+		if options.Permissions == nil {
+			violations = append(violations, "spec.permissions must be set")
+		} else {
+			for _, exceeded := range PermissionsExceeding(options.Permissions, rules.Permissions.ToInstallationPermissions()) {
+				violations = append(violations, "permission "+exceeded+" is not allowed")
+			}
+		}
+	}
+
+	if len(rules.Repositories) > 0 {
+		switch {
+		case len(options.RepositoryIDs) > 0:
+			violations = append(violations, "spec.repositoryIDs must not be set")
+		case owner.GetRepositorySelector() != nil:
+			// The selected repositories change outside the cluster, so
+			// cannot be held to the policy at admission.
+			violations = append(violations, "spec.repositorySelector must not be set")
+		case len(options.Repositories) == 0:
+			violations = append(violations, "spec.repositories must be set")
+		}
+		for _, repository := range options.Repositories {
+			if !matchAny(rules.Repositories, repository) {
+				violations = append(violations, fmt.Sprintf("repository %q is not allowed", repository))
+			}
+		}
+	}
+
+	if len(rules.Apps) > 0 {
+		switch appRef := owner.GetAppRef(); {
+		case appRef == nil:
+			if !rules.AllowStartupApp {
+				violations = append(violations, "spec.appRef must be set")
+			}
+		default:
+			// Apps shared from another namespace under an AppGrant are
+			// matched by their namespace-qualified name.
+			name := appRef.Name
+			if appRef.Namespace != owner.GetNamespace() {
+				name = appRef.Namespace + "/" + appRef.Name
+			}
+			if !matchAny(rules.Apps, name) {
+				violations = append(violations, fmt.Sprintf("App %q is not allowed", name))
+			}
+		}
+	}
+
+	return violations
+}
+
+// matchAny reports whether name matches any of patterns. Malformed patterns
+// match nothing.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionsExceeding lists, as "name=level", each permission in requested
+// that is absent from limit or granted at a higher level than limit allows.
+func PermissionsExceeding(requested, limit *github.InstallationPermissions) []string {
+	if requested == nil {
+		return nil
+	}
+	if limit == nil {
+		limit = &github.InstallationPermissions{}
+	}
+
+	var exceeded []string
+	want := reflect.ValueOf(requested).Elem()
+	have := reflect.ValueOf(limit).Elem()
+	for i := range want.NumField() {
+		field := want.Type().Field(i)
+		level, ok := want.Field(i).Interface().(*string)
+		if !ok || level == nil {
+			continue
+		}
+		allowed, _ := have.Field(i).Interface().(*string)
+		if allowed == nil || permissionLevels[*level] > permissionLevels[*allowed] {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			exceeded = append(exceeded, name+"="+*level)
+		}
+	}
+	return exceeded
+}
@@ -0,0 +1,193 @@
+package policy
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v84/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+)
+
+func testClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := githubv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func TestPermissionsExceeding(t *testing.T) {
+	requested := &github.InstallationPermissions{
+		Contents:       github.Ptr("write"),
+		Metadata:       github.Ptr("read"),
+		Administration: github.Ptr("write"),
+		Issues:         github.Ptr("read"),
+	}
+	limit := &github.InstallationPermissions{
+		Contents: github.Ptr("read"),
+		Metadata: github.Ptr("read"),
+		Issues:   github.Ptr("write"),
+	}
+
+	got := PermissionsExceeding(requested, limit)
+	want := []string{"administration=write", "contents=write"}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("PermissionsExceeding() = %v, want %v", got, want)
+	}
+
+	if got := PermissionsExceeding(nil, limit); got != nil {
+		t.Errorf("PermissionsExceeding(nil) = %v, want nil", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	rules := &githubv1.TokenPolicyRules{
+		Permissions:  &githubv1.Permissions{Contents: github.Ptr("read"), Metadata: github.Ptr("read")},
+		Repositories: []string{"infra-*", "docs"},
+		Apps:         []string{"team-*"},
+	}
+
+	tests := []struct {
+		name string
+		spec githubv1.TokenSpec
+		want []string
+	}{
+		{
+			name: "compliant",
+			spec: githubv1.TokenSpec{
+				AppRef:       &githubv1.LocalAppReference{Name: "team-app"},
+				Permissions:  &githubv1.Permissions{Contents: github.Ptr("read")},
+				Repositories: []string{"infra-live", "docs"},
+			},
+		},
+		{
+			name: "everything omitted",
+			spec: githubv1.TokenSpec{},
+			want: []string{"spec.permissions must be set", "spec.repositories must be set", "spec.appRef must be set"},
+		},
+		{
+			name: "everything exceeded",
+			spec: githubv1.TokenSpec{
+				AppRef:        &githubv1.LocalAppReference{Name: "platform"},
+				Permissions:   &githubv1.Permissions{Contents: github.Ptr("write")},
+				Repositories:  []string{"app"},
+				RepositoryIDs: []int64{42},
+			},
+			want: []string{
+				"permission contents=write is not allowed",
+				"spec.repositoryIDs must not be set",
+				`repository "app" is not allowed`,
+				`App "platform" is not allowed`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &githubv1.Token{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ci"}, Spec: tt.spec}
+			if got := Check(rules, token); !slices.Equal(got, tt.want) {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck_SharedApp(t *testing.T) {
+	rules := &githubv1.TokenPolicyRules{Apps: []string{"platform/*"}}
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ci"},
+		Spec: githubv1.TokenSpec{
+			AppRef: &githubv1.LocalAppReference{Namespace: "platform", Name: "shared-app"},
+		},
+	}
+	if got := Check(rules, token); len(got) != 0 {
+		t.Errorf("Check() = %q, want no violations", got)
+	}
+
+	token.Spec.AppRef.Namespace = ""
+	want := []string{`App "shared-app" is not allowed`}
+	if got := Check(rules, token); !slices.Equal(got, want) {
+		t.Errorf("Check() = %q, want %q", got, want)
+	}
+}
+
+func TestCheck_AllowStartupApp(t *testing.T) {
+	rules := &githubv1.TokenPolicyRules{Apps: []string{"team-app"}, AllowStartupApp: true}
+	token := &githubv1.Token{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ci"}}
+	if got := Check(rules, token); len(got) != 0 {
+		t.Errorf("Check() = %q, want no violations", got)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	readOnly := githubv1.TokenPolicyRules{
+		Permissions: &githubv1.Permissions{Contents: github.Ptr("read")},
+	}
+	objects := []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant", Labels: map[string]string{"tenant": "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "platform"}},
+		&githubv1.TokenPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "platform", Name: "repos"},
+			Spec:       githubv1.TokenPolicySpec{TokenPolicyRules: githubv1.TokenPolicyRules{Repositories: []string{"infra"}}},
+		},
+		&githubv1.ClusterTokenPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "tenants"},
+			Spec: githubv1.ClusterTokenPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+				TokenPolicyRules:  readOnly,
+			},
+		},
+	}
+	c := testClient(t, objects...)
+
+	tests := []struct {
+		name      string
+		owner     *githubv1.Token
+		wantMatch string
+	}{
+		{
+			name:      "cluster policy selects tenant namespace",
+			owner:     &githubv1.Token{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant", Name: "ci"}},
+			wantMatch: "ClusterTokenPolicy tenants: spec.permissions must be set",
+		},
+		{
+			name: "namespaced policy applies in its own namespace only",
+			owner: &githubv1.Token{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "platform", Name: "ci"},
+				Spec:       githubv1.TokenSpec{Repositories: []string{"app"}},
+			},
+			wantMatch: `TokenPolicy repos: repository "app" is not allowed`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(context.Background(), c, tt.owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || !strings.Contains(got[0], tt.wantMatch) {
+				t.Errorf("Evaluate() = %q, want one violation matching %q", got, tt.wantMatch)
+			}
+		})
+	}
+
+	clusterToken := &githubv1.ClusterToken{ObjectMeta: metav1.ObjectMeta{Name: "admin"}}
+	if got, err := Evaluate(context.Background(), c, clusterToken); err != nil || got != nil {
+		t.Errorf("Evaluate(ClusterToken) = %q, %v; want no violations", got, err)
+	}
+}
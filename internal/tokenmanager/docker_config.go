@@ -0,0 +1,28 @@
+package tokenmanager
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// dockerAuth is a single entry in the auths map of a docker config JSON.
+type dockerAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+// dockerConfigJSON returns the '.dockerconfigjson' payload authenticating
+// each registry with the installation token.
+func dockerConfigJSON(registries []string, installationToken string) ([]byte, error) {
+	auth := dockerAuth{
+		Username: BasicAuthUsername,
+		Password: installationToken,
+		Auth:     base64.StdEncoding.EncodeToString([]byte(BasicAuthUsername + ":" + installationToken)),
+	}
+	auths := make(map[string]dockerAuth, len(registries))
+	for _, registry := range registries {
+		auths[registry] = auth
+	}
+	return json.Marshal(map[string]map[string]dockerAuth{"auths": auths})
+}
@@ -0,0 +1,31 @@
+package tokenmanager
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestDockerConfigJSON(t *testing.T) {
+	data, err := dockerConfigJSON([]string{"ghcr.io", "containers.example.com"}, "ghs_abc")
+	if err != nil {
+		t.Fatalf("dockerConfigJSON() err = %v", err)
+	}
+
+	var config struct {
+		Auths map[string]dockerAuth `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(config.Auths) != 2 {
+		t.Fatalf("auths = %v, want 2 entries", config.Auths)
+	}
+
+	wantAuth := base64.StdEncoding.EncodeToString([]byte("x-access-token:ghs_abc"))
+	for registry, auth := range config.Auths {
+		if auth.Username != BasicAuthUsername || auth.Password != "ghs_abc" || auth.Auth != wantAuth {
+			t.Errorf("auths[%q] = %+v, want username/password/auth for the token", registry, auth)
+		}
+	}
+}
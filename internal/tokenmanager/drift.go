@@ -0,0 +1,176 @@
+package tokenmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+	corev1 "k8s.io/api/core/v1"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/metrics"
+)
+
+const (
+	// AnnotationExpiresAt records when the installation token held by a
+	// managed Secret expires.
+	AnnotationExpiresAt = "github.as-code.io/expires-at"
+	// AnnotationChecksum records a digest of the data written to a managed
+	// Secret, so that tampering can be told apart from the operator's own
+	// writes without minting a fresh token.
+	AnnotationChecksum = "github.as-code.io/checksum"
+)
+
+// secretChecksum digests everything that determines the content of a managed
+// Secret: its data and token expiry, the parts of the owner's spec that shape
+// the token and its data, the GitHub App identity the token was minted for
+// and, for a repository selector, the repositories it resolved to, so that a
+// change in them forces a new token. Spec changes to the Secret's labels and
+// annotations alone leave it unchanged, so are applied without minting.
+func (s *tokenSecret) secretChecksum(data map[string][]byte, expiresAt string) string {
+	h := sha256.New()
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write(data[key])
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(expiresAt))
+	h.Write([]byte{0})
+	h.Write(s.tokenSpec())
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(s.ghait.GetAppID(), 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(s.installationID(), 10)))
+	for _, repository := range s.repositories {
+		h.Write([]byte{0})
+		h.Write([]byte(repository))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// tokenSpec returns a canonical encoding of the parts of the owner's spec that
+// determine the token requested and the data written for it.
+func (s *tokenSecret) tokenSpec() []byte {
+	spec, _ := json.Marshal(struct {
+		Options          *github.InstallationTokenOptions `json:"options"`
+		BasicAuth        bool                             `json:"basicAuth"`
+		Template         map[string]string                `json:"template"`
+		DockerConfigJSON *githubv1.DockerConfigJSONSpec   `json:"dockerConfigJSON"`
+		ArgoCD           *githubv1.ArgoCDSpec             `json:"argoCD"`
+		GitFormats       []githubv1.GitFormat             `json:"gitFormats"`
+		GitHost          string                           `json:"gitHost"`
+	}{
+		Options:          s.owner.GetInstallationTokenOptions(),
+		BasicAuth:        s.owner.GetSecretBasicAuth(),
+		Template:         s.owner.GetSecretTemplate(),
+		DockerConfigJSON: s.owner.GetSecretDockerConfigJSON(),
+		ArgoCD:           s.owner.GetSecretArgoCD(),
+		GitFormats:       s.owner.GetSecretGitFormats(),
+		GitHost:          s.owner.GetSecretGitHost(),
+	})
+	return spec
+}
+
+// installationID returns the installation the owner's tokens are minted for.
+func (s *tokenSecret) installationID() int64 {
+	if installationID := s.owner.GetInstallationID(); installationID != 0 {
+		return installationID
+	}
+	if s.installation != 0 {
+		return s.installation
+	}
+	return s.ghait.GetInstallationID()
+}
+
+// SecretAnnotations returns the annotations the operator maintains on a
+// managed Secret holding data for a token expiring at expiresAt.
+func (s *tokenSecret) SecretAnnotations(data map[string][]byte, expiresAt time.Time) map[string]string {
+	expiry := expiresAt.UTC().Format(time.RFC3339)
+	return map[string]string{
+		AnnotationExpiresAt: expiry,
+		AnnotationChecksum:  s.secretChecksum(data, expiry),
+	}
+}
+
+// secretIntact reports whether the data of an existing managed Secret is
+// exactly what the operator last wrote for the current spec.
+func (s *tokenSecret) secretIntact(secret *corev1.Secret) bool {
+	checksum, ok := secret.Annotations[AnnotationChecksum]
+	if !ok {
+		return false
+	}
+	return checksum == s.secretChecksum(secret.Data, secret.Annotations[AnnotationExpiresAt])
+}
+
+// freshFor returns how long the token held by an existing managed Secret
+// can be kept before it is due for refresh, or zero if it must be replaced
+// now: it is already due, its expiry is unknown, or the Secret's data is not
+// exactly what the operator last wrote for the current spec. It depends only
+// on the Secret itself, so a token minted before an operator restart is kept.
+func (s *tokenSecret) freshFor(secret *corev1.Secret) time.Duration {
+	if !s.secretIntact(secret) {
+		return 0
+	}
+	expiresAt, err := time.Parse(time.RFC3339, secret.Annotations[AnnotationExpiresAt])
+	if err != nil {
+		return 0
+	}
+	return max(time.Until(s.refreshAt(expiresAt)), 0)
+}
+
+// refreshAt returns when a token expiring at expiresAt is due for refresh:
+// the owner's refresh window before it expires, brought forward by a jitter
+// of up to the owner's refresh jitter. The jitter is drawn from a hash of the
+// owner's UID and the expiry, so it is stable across reconciles and operator
+// restarts yet spreads the refreshes of tokens minted at the same moment.
+func (s *tokenSecret) refreshAt(expiresAt time.Time) time.Time {
+	refreshAt := expiresAt.Add(-s.owner.GetRefreshWindow())
+	if jitter := s.owner.GetRefreshJitter(); jitter > 0 {
+		h := fnv.New64a()
+		h.Write([]byte(s.owner.GetUID()))
+		h.Write([]byte{0})
+		h.Write([]byte(expiresAt.UTC().Format(time.RFC3339)))
+		refreshAt = refreshAt.Add(-time.Duration(h.Sum64() % uint64(jitter)))
+	}
+	return refreshAt
+}
+
+// nextRefresh returns how long until the token last recorded in the owner's
+// status is due for refresh, falling back to the refresh interval if no
+// expiry is recorded. It is never less than a second, so that a token already
+// due is not refreshed in a tight loop.
+func (s *tokenSecret) nextRefresh() time.Duration {
+	_, expiresAt := s.owner.GetStatusTimestamps()
+	if expiresAt.IsZero() {
+		return s.owner.GetRefreshInterval()
+	}
+	return max(time.Until(s.refreshAt(expiresAt)), time.Second)
+}
+
+// restoreMetadata reverts any change to the operator-managed labels and
+// annotations of secret, and applies those changed in the spec, without
+// touching its data.
+func (s *tokenSecret) restoreMetadata(ctx context.Context, secret *corev1.Secret) error {
+	log := s.log.WithValues("func", "restoreMetadata")
+
+	if !s.applyMetadata(secret) {
+		return nil
+	}
+
+	log.Info("updating managed metadata", "secret", secret.Namespace+"/"+secret.Name)
+	if err := s.client.Update(ctx, secret); err != nil {
+		log.Error(err, "failed to update metadata")
+		s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationUpdate, metrics.ResultError)
+		return err
+	}
+	s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationUpdate, metrics.ResultSuccess)
+	return nil
+}
@@ -0,0 +1,430 @@
+package tokenmanager
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-github/v84/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/isometry/ghait/v84"
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/ghapp"
+)
+
+func TestReconcile_RestoresDrift(t *testing.T) {
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "drift", UID: "uid-drift"},
+		Spec: githubv1.TokenSpec{
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(token).WithStatusSubresource(token).Build()
+	ctx := context.Background()
+	key := client.ObjectKeyFromObject(token)
+	gh := &fakeGHAIT{}
+
+	reconcile := func() {
+		t.Helper()
+		owner := &githubv1.Token{}
+		if err := c.Get(ctx, key, owner); err != nil {
+			t.Fatal(err)
+		}
+		result, err := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(gh)).Reconcile(ctx)
+		if err != nil {
+			t.Fatalf("Reconcile() err = %v", err)
+		}
+		if result.RequeueAfter <= 0 || result.RequeueAfter > 30*time.Minute {
+			t.Errorf("RequeueAfter = %v, want within the refresh interval", result.RequeueAfter)
+		}
+	}
+	secret := func() *corev1.Secret {
+		t.Helper()
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, key, secret); err != nil {
+			t.Fatal(err)
+		}
+		return secret
+	}
+
+	reconcile()
+	if gh.mints != 1 {
+		t.Fatalf("mints after create = %d, want 1", gh.mints)
+	}
+
+	// An event for the operator's own write must not mint again.
+	reconcile()
+	if gh.mints != 1 {
+		t.Errorf("mints after no-op reconcile = %d, want 1", gh.mints)
+	}
+
+	// Label drift is reverted without minting.
+	drifted := secret()
+	delete(drifted.Labels, "app.kubernetes.io/instance")
+	if err := c.Update(ctx, drifted); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if gh.mints != 1 {
+		t.Errorf("mints after label drift = %d, want 1", gh.mints)
+	}
+	if got := secret().Labels["app.kubernetes.io/instance"]; got != "drift" {
+		t.Errorf("instance label = %q, want drift", got)
+	}
+
+	// Tampered data is rewritten with a fresh token.
+	tampered := secret()
+	tampered.Data["token"] = []byte("tampered")
+	if err := c.Update(ctx, tampered); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if gh.mints != 2 {
+		t.Errorf("mints after tampering = %d, want 2", gh.mints)
+	}
+	if got := string(secret().Data["token"]); got != "ghs_test" {
+		t.Errorf("token = %q, want ghs_test", got)
+	}
+
+	// A deleted Secret is recreated.
+	if err := c.Delete(ctx, secret()); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if gh.mints != 3 {
+		t.Errorf("mints after deletion = %d, want 3", gh.mints)
+	}
+	secret()
+}
+
+func TestReconcile_RemintsOnRepositorySelectionChange(t *testing.T) {
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "selected", UID: "uid-selected"},
+		Spec: githubv1.TokenSpec{
+			RefreshInterval:    metav1.Duration{Duration: 30 * time.Minute},
+			RepositorySelector: &githubv1.RepositorySelector{Names: []string{"service-*"}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(token).WithStatusSubresource(token).Build()
+	ctx := context.Background()
+	key := client.ObjectKeyFromObject(token)
+	gh := &fakeGHAIT{}
+
+	reconcile := func(repositories ...string) {
+		t.Helper()
+		owner := &githubv1.Token{}
+		if err := c.Get(ctx, key, owner); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(gh), WithRepositories(repositories)).Reconcile(ctx); err != nil {
+			t.Fatalf("Reconcile() err = %v", err)
+		}
+	}
+	selected := func() []string {
+		t.Helper()
+		owner := &githubv1.Token{}
+		if err := c.Get(ctx, key, owner); err != nil {
+			t.Fatal(err)
+		}
+		return owner.Status.SelectedRepositories
+	}
+
+	reconcile("service-a")
+	if gh.mints != 1 || !slices.Equal(gh.options.Repositories, []string{"service-a"}) {
+		t.Fatalf("mints = %d with repositories %v, want 1 with [service-a]", gh.mints, gh.options.Repositories)
+	}
+	if got := selected(); !slices.Equal(got, []string{"service-a"}) {
+		t.Errorf("status.selectedRepositories = %v, want [service-a]", got)
+	}
+
+	reconcile("service-a")
+	if gh.mints != 1 {
+		t.Errorf("mints after unchanged selection = %d, want 1", gh.mints)
+	}
+
+	reconcile("service-a", "service-b")
+	if gh.mints != 2 || !slices.Equal(gh.options.Repositories, []string{"service-a", "service-b"}) {
+		t.Errorf("mints = %d with repositories %v, want 2 with [service-a service-b]", gh.mints, gh.options.Repositories)
+	}
+	if got := selected(); !slices.Equal(got, []string{"service-a", "service-b"}) {
+		t.Errorf("status.selectedRepositories = %v, want [service-a service-b]", got)
+	}
+}
+
+func TestReconcile_SchedulesRefreshFromExpiry(t *testing.T) {
+	ctx := context.Background()
+	gh := &fakeGHAIT{}
+	reconcile := func(c client.Client, key client.ObjectKey) time.Duration {
+		t.Helper()
+		owner := &githubv1.Token{}
+		if err := c.Get(ctx, key, owner); err != nil {
+			t.Fatal(err)
+		}
+		result, err := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(gh)).Reconcile(ctx)
+		if err != nil {
+			t.Fatalf("Reconcile() err = %v", err)
+		}
+		return result.RequeueAfter
+	}
+
+	var scheduled []time.Duration
+	for _, name := range []string{"a", "b", "c", "d"} {
+		token := &githubv1.Token{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID("uid-" + name)},
+			Spec: githubv1.TokenSpec{
+				RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+				RefreshWindow:   &metav1.Duration{Duration: 10 * time.Minute},
+				RefreshJitter:   &metav1.Duration{Duration: 5 * time.Minute},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(token).WithStatusSubresource(token).Build()
+		key := client.ObjectKeyFromObject(token)
+
+		requeueAfter := reconcile(c, key)
+		if requeueAfter < 44*time.Minute || requeueAfter > 50*time.Minute {
+			t.Errorf("%s: RequeueAfter = %v, want within the 5m jitter of 50m", name, requeueAfter)
+		}
+		scheduled = append(scheduled, requeueAfter.Round(time.Second))
+
+		// A restarted operator schedules the same refresh without minting.
+		mints := gh.mints
+		if again := reconcile(c, key); (again - requeueAfter).Abs() > 2*time.Second {
+			t.Errorf("%s: RequeueAfter after restart = %v, want %v", name, again, requeueAfter)
+		}
+		if gh.mints != mints {
+			t.Errorf("%s: mints after restart = %d, want %d", name, gh.mints, mints)
+		}
+	}
+
+	slices.Sort(scheduled)
+	if len(slices.Compact(scheduled)) == 1 {
+		t.Errorf("RequeueAfter = %v for every token, want refreshes spread by jitter", scheduled[0])
+	}
+}
+
+func TestReconcile_KeepsTokenOnMetadataChange(t *testing.T) {
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "meta", UID: "uid-meta"},
+		Spec: githubv1.TokenSpec{
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+			Secret: githubv1.TokenSecretSpec{
+				Annotations: map[string]string{"team": "platform"},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(token).WithStatusSubresource(token).Build()
+	ctx := context.Background()
+	key := client.ObjectKeyFromObject(token)
+	gh := &fakeGHAIT{}
+
+	reconcile := func(mutate func(*githubv1.Token)) *corev1.Secret {
+		t.Helper()
+		owner := &githubv1.Token{}
+		if err := c.Get(ctx, key, owner); err != nil {
+			t.Fatal(err)
+		}
+		if mutate != nil {
+			mutate(owner)
+			owner.Generation++
+			if err := c.Update(ctx, owner); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(gh)).Reconcile(ctx); err != nil {
+			t.Fatalf("Reconcile() err = %v", err)
+		}
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, key, secret); err != nil {
+			t.Fatal(err)
+		}
+		return secret
+	}
+
+	if secret := reconcile(nil); secret.Annotations["team"] != "platform" {
+		t.Errorf("annotations = %v, want team=platform", secret.Annotations)
+	}
+
+	// Label and annotation edits are applied to the existing Secret.
+	secret := reconcile(func(tok *githubv1.Token) {
+		tok.Spec.Secret.Labels = map[string]string{"tier": "backend"}
+		tok.Spec.Secret.Annotations = map[string]string{"owner": "alice"}
+	})
+	if gh.mints != 1 {
+		t.Errorf("mints after metadata change = %d, want 1", gh.mints)
+	}
+	if secret.Labels["tier"] != "backend" {
+		t.Errorf("labels = %v, want tier=backend", secret.Labels)
+	}
+	if _, ok := secret.Annotations["team"]; ok || secret.Annotations["owner"] != "alice" {
+		t.Errorf("annotations = %v, want team removed and owner=alice", secret.Annotations)
+	}
+
+	// A change to the token's scope mints afresh.
+	reconcile(func(tok *githubv1.Token) {
+		tok.Spec.Permissions = &githubv1.Permissions{Contents: github.Ptr("read")}
+	})
+	if gh.mints != 2 {
+		t.Errorf("mints after permissions change = %d, want 2", gh.mints)
+	}
+}
+
+func TestReconcile_KeepsTokenAcrossRestart(t *testing.T) {
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "restart", UID: "uid-restart"},
+		Spec:       githubv1.TokenSpec{RefreshInterval: metav1.Duration{Duration: 30 * time.Minute}},
+	}
+	clusterToken := &githubv1.ClusterToken{
+		ObjectMeta: metav1.ObjectMeta{Name: "restart", UID: "uid-cluster-restart"},
+		Spec: githubv1.ClusterTokenSpec{
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+			Secret: githubv1.ClusterTokenSecretSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(testScheme(t)).
+		WithObjects(
+			token, clusterToken,
+			namespace("team-a", map[string]string{"tenant": "true"}),
+			namespace("team-b", map[string]string{"tenant": "true"}),
+		).
+		WithStatusSubresource(token, clusterToken).
+		Build()
+	ctx := context.Background()
+
+	// Each reconcile stands in for a freshly started operator: the owner is
+	// read back from the API server and nothing is carried over in memory.
+	tests := []struct {
+		name    string
+		key     types.NamespacedName
+		owner   func() TokenManager
+		secrets []types.NamespacedName
+	}{
+		{
+			name:    "single Secret",
+			key:     client.ObjectKeyFromObject(token),
+			owner:   func() TokenManager { return &githubv1.Token{} },
+			secrets: []types.NamespacedName{{Namespace: "default", Name: "restart"}},
+		},
+		{
+			name:  "fan-out",
+			key:   client.ObjectKeyFromObject(clusterToken),
+			owner: func() TokenManager { return &githubv1.ClusterToken{} },
+			secrets: []types.NamespacedName{
+				{Namespace: "team-a", Name: "restart"},
+				{Namespace: "team-b", Name: "restart"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := func() int {
+				t.Helper()
+				owner := tt.owner()
+				if err := c.Get(ctx, tt.key, owner); err != nil {
+					t.Fatal(err)
+				}
+				gh := &fakeGHAIT{}
+				if _, err := NewTokenSecret(tt.key, owner, "test", WithClient(c), WithGHApp(gh)).Reconcile(ctx); err != nil {
+					t.Fatalf("Reconcile() err = %v", err)
+				}
+				return gh.mints
+			}
+
+			if mints := start(); mints != 1 {
+				t.Fatalf("mints on first start = %d, want 1", mints)
+			}
+			if mints := start(); mints != 0 {
+				t.Errorf("mints after restart = %d, want 0 while the token is fresh", mints)
+			}
+
+			// Data edited while the operator was down is not kept.
+			secret := &corev1.Secret{}
+			if err := c.Get(ctx, tt.secrets[len(tt.secrets)-1], secret); err != nil {
+				t.Fatal(err)
+			}
+			secret.Data["token"] = []byte("tampered")
+			if err := c.Update(ctx, secret); err != nil {
+				t.Fatal(err)
+			}
+			if mints := start(); mints != 1 {
+				t.Errorf("mints after restart with edited data = %d, want 1", mints)
+			}
+			for _, key := range tt.secrets {
+				if err := c.Get(ctx, key, secret); err != nil {
+					t.Fatal(err)
+				}
+				if got := string(secret.Data["token"]); got != "ghs_test" {
+					t.Errorf("%s token = %q, want ghs_test", key, got)
+				}
+			}
+		})
+	}
+}
+
+// fakeSigner stands in for the App's key provider.
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(jwt.Claims) (string, error) { return "signed", nil }
+
+func withFakeSigner() ghapp.Option {
+	return ghapp.WithSigner(func(context.Context, *ghapp.OperatorConfig) (ghinstallation.Signer, error) {
+		return fakeSigner{}, nil
+	})
+}
+
+func TestReconcile_SharesToken(t *testing.T) {
+	ctx := context.Background()
+	gh := &fakeGHAIT{}
+	reg := ghapp.NewRegistry("gtm-system", &ghapp.OperatorConfig{AppID: 1},
+		withFakeSigner(),
+		ghapp.WithFactory(func(context.Context, *ghapp.OperatorConfig, *github.Client) (ghait.GHAIT, error) {
+			return gh, nil
+		}))
+	if _, err := reg.Startup(ctx); err != nil {
+		t.Fatalf("Startup() err = %v", err)
+	}
+
+	newToken := func(name string, share bool) *githubv1.Token {
+		return &githubv1.Token{
+			ObjectMeta: metav1.ObjectMeta{Namespace: name, Name: "shared", UID: types.UID("uid-" + name)},
+			Spec: githubv1.TokenSpec{
+				RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+				Permissions:     &githubv1.Permissions{Contents: github.Ptr("read")},
+				ShareToken:      share,
+			},
+		}
+	}
+	tokens := []*githubv1.Token{newToken("team-a", true), newToken("team-b", true), newToken("team-c", false)}
+	c := fake.NewClientBuilder().WithScheme(testScheme(t)).
+		WithObjects(tokens[0], tokens[1], tokens[2]).
+		WithStatusSubresource(tokens[0], tokens[1], tokens[2]).
+		Build()
+
+	for _, token := range tokens {
+		key := client.ObjectKeyFromObject(token)
+		owner := &githubv1.Token{}
+		if err := c.Get(ctx, key, owner); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(gh), WithSharedTokens(reg.SharedTokens(ghapp.StartupKey))).Reconcile(ctx); err != nil {
+			t.Fatalf("Reconcile(%s) err = %v", key, err)
+		}
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, key, secret); err != nil {
+			t.Fatalf("Secret %s: %v", key, err)
+		}
+	}
+	if gh.mints != 2 {
+		t.Errorf("mints = %d, want 2: one shared by team-a and team-b, one for team-c", gh.mints)
+	}
+}
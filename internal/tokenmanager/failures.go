@@ -0,0 +1,75 @@
+package tokenmanager
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/backoff"
+)
+
+// WithBackoff sets the policy failed reconciles are retried by, also capped
+// at the owner's spec.retryInterval.
+func WithBackoff(policy backoff.Policy) Option {
+	return func(s *tokenSecret) {
+		s.retryPolicy = policy
+	}
+}
+
+// RetryAfter returns how long to wait before retrying owner after the
+// failures in a row recorded in its status: the delay of policy, also capped
+// at the owner's spec.retryInterval.
+func RetryAfter(policy backoff.Policy, owner TokenManager) time.Duration {
+	return policy.WithMaxDelay(owner.GetRetryInterval()).Delay(owner.GetStatusFailures().ConsecutiveFailures)
+}
+
+// failed records a failed reconcile in the owner's status, along with
+// condition unless nil, and requeues the owner once it has backed off for
+// its failures in a row, but no sooner than floor.
+func (s *tokenSecret) failed(ctx context.Context, condition *metav1.Condition, floor time.Duration) (reconcile.Result, error) {
+	if err := s.recordFailure(ctx, condition); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: max(RetryAfter(s.retryPolicy, s.owner), floor)}, nil
+}
+
+// apiFailed records a failed Kubernetes API call, described by msg, as a
+// failed reconcile counted under reason, surfacing it on the owner's Ready
+// condition and backing off like any other failure.
+func (s *tokenSecret) apiFailed(ctx context.Context, err error, msg, reason string) (reconcile.Result, error) {
+	s.metrics.RecordReconcileError(ctx, s.controllerName, reason)
+	s.log.Error(err, msg)
+	condition := metav1.Condition{
+		Type:    githubv1.ConditionTypeReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Failed",
+		Message: msg + ": " + err.Error(),
+	}
+	return s.failed(ctx, &condition, 0)
+}
+
+// recordFailure refreshes the owner, counts a failed reconcile in its status
+// and sets condition unless nil, retrying on conflict.
+func (s *tokenSecret) recordFailure(ctx context.Context, condition *metav1.Condition) error {
+	log := s.log.WithValues("func", "recordFailure")
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := s.RefreshOwner(ctx); err != nil {
+			return err
+		}
+		if condition != nil {
+			s.owner.SetStatusCondition(*condition)
+		}
+		s.owner.GetStatusFailures().RecordFailure(time.Now())
+		return s.client.Status().Update(ctx, s.owner)
+	})
+	if err != nil {
+		log.Error(err, "failed to update token status")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package tokenmanager
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/backoff"
+)
+
+func TestRetryAfter(t *testing.T) {
+	policy := backoff.Policy{BaseDelay: time.Second, MaxDelay: 20 * time.Second}
+	tests := []struct {
+		name          string
+		retryInterval time.Duration
+		failures      int32
+		want          time.Duration
+	}{
+		{name: "first failure", failures: 1, want: time.Second},
+		{name: "capped by the policy", failures: 10, want: 20 * time.Second},
+		{name: "capped by spec.retryInterval", retryInterval: 5 * time.Second, failures: 10, want: 5 * time.Second},
+		{name: "spec.retryInterval beyond the policy", retryInterval: time.Minute, failures: 10, want: 20 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &githubv1.Token{Spec: githubv1.TokenSpec{RetryInterval: metav1.Duration{Duration: tt.retryInterval}}}
+			token.Status.ConsecutiveFailures = tt.failures
+			if got := RetryAfter(policy, token); got != tt.want {
+				t.Errorf("RetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,481 @@
+package tokenmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/isometry/ghait/v84"
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/events"
+	"github.com/isometry/github-token-manager/internal/metrics"
+)
+
+// fanOutOwner returns the owner as a [FanOutTokenManager] when it has a
+// namespace selector set.
+func (s *tokenSecret) fanOutOwner() (FanOutTokenManager, bool) {
+	owner, ok := s.owner.(FanOutTokenManager)
+	if !ok || owner.GetSecretNamespaceSelector() == nil {
+		return nil, false
+	}
+	return owner, true
+}
+
+// reconcileFanOut mints a single installation token and writes a copy of the
+// Secret into every namespace matching the owner's namespace selector,
+// deleting copies from namespaces that no longer match.
+func (s *tokenSecret) reconcileFanOut(ctx context.Context, owner FanOutTokenManager) (result reconcile.Result, err error) {
+	log := s.log.WithValues("func", "reconcileFanOut")
+
+	targets, err := s.targetNamespaces(ctx, owner)
+	if err != nil {
+		return s.apiFailed(ctx, err, "failed to list target namespaces", metrics.ReasonSecretUpdate)
+	}
+
+	existing, err := s.fanOutSecrets(ctx)
+	if err != nil {
+		return s.apiFailed(ctx, err, "failed to list managed secrets", metrics.ReasonSecretUpdate)
+	}
+
+	for _, secret := range existing {
+		if s.fanOutTarget(secret, targets) {
+			continue
+		}
+		if err := s.deleteFanOutSecret(ctx, secret); err != nil {
+			return s.apiFailed(ctx, err, "failed to delete managed secret", metrics.ReasonSecretUpdate)
+		}
+	}
+	existing = slices.DeleteFunc(existing, func(secret corev1.Secret) bool {
+		return !s.fanOutTarget(secret, targets)
+	})
+
+	dueIn, conflicts, unlabelled, err := s.fanOutDueIn(ctx, targets, existing)
+	if err != nil {
+		return s.apiFailed(ctx, err, "failed to get secrets", metrics.ReasonSecretUpdate)
+	}
+	if len(targets) == len(conflicts) {
+		// No namespace can take a copy, so a token would only be discarded.
+		if err := s.updateFanOutStatus(ctx, owner, s.fanOutCondition(ctx, nil, conflicts), nil, nil); err != nil {
+			log.Error(err, "failed to update token status")
+			return result, err
+		}
+		s.metrics.RemoveTokenActive(ctx, s.controllerName, s.key.String())
+		return reconcile.Result{RequeueAfter: s.owner.GetRefreshInterval()}, nil
+	}
+	if dueIn > 0 {
+		// Every copy is intact and the token is still fresh.
+		existing = append(existing, unlabelled...)
+		for i := range existing {
+			if err := s.restoreMetadata(ctx, &existing[i]); err != nil {
+				return s.apiFailed(ctx, err, "failed to update secret metadata", metrics.ReasonSecretUpdate)
+			}
+		}
+		written := slices.DeleteFunc(slices.Clone(targets), func(namespace string) bool {
+			return slices.Contains(conflicts, namespace)
+		})
+		if err := s.updateFanOutStatus(ctx, owner, s.fanOutCondition(ctx, written, conflicts), nil, written); err != nil {
+			log.Error(err, "failed to update token status")
+			return result, err
+		}
+		s.metrics.RecordMintSkipped(ctx, s.controllerName)
+		s.metrics.EnsureTokenActive(ctx, s.controllerName, s.key.String())
+		return reconcile.Result{RequeueAfter: dueIn}, nil
+	}
+
+	start := time.Now()
+	installationToken, conflicts, err := s.writeFanOutSecrets(ctx, targets)
+	if err != nil {
+		s.metrics.RecordTokenRefresh(ctx, s.controllerName, metrics.ResultError)
+		s.metrics.RecordTokenRefreshDuration(ctx, s.controllerName, metrics.OperationUpdate, time.Since(start))
+		if failure := classifyGitHubError(err, s.installationID()); failure != nil {
+			return s.githubFailed(ctx, failure)
+		}
+		if errors.Is(err, ghait.TransientError{}) {
+			s.metrics.RecordReconcileError(ctx, s.controllerName, metrics.ReasonTransient)
+			s.events.Warning(s.owner, events.ReasonTransientFailure, events.ActionMintToken, err.Error())
+			log.Error(err, "transient error writing secrets")
+			return s.failed(ctx, nil, 0)
+		}
+		if templateErr := (*TemplateError)(nil); errors.As(err, &templateErr) {
+			return result, s.templateFailed(ctx, templateErr)
+		}
+
+		s.metrics.RecordReconcileError(ctx, s.controllerName, metrics.ReasonSecretUpdate)
+		log.Error(err, "fatal error writing secrets")
+		return s.failed(ctx, nil, 0)
+	}
+
+	written := slices.DeleteFunc(slices.Clone(targets), func(namespace string) bool {
+		return slices.Contains(conflicts, namespace)
+	})
+
+	if err := s.updateFanOutStatus(ctx, owner, s.fanOutCondition(ctx, written, conflicts), installationToken, written); err != nil {
+		log.Error(err, "failed to update token status")
+		return result, err
+	}
+
+	if len(written) > 0 {
+		s.metrics.RecordTokenRefresh(ctx, s.controllerName, metrics.ResultSuccess)
+		s.metrics.RecordTokenRefreshDuration(ctx, s.controllerName, metrics.OperationUpdate, time.Since(start))
+		s.metrics.EnsureTokenActive(ctx, s.controllerName, s.key.String())
+		s.recordExpiry(ctx)
+	} else {
+		// No token was minted, so the expiry in status is stale.
+		s.metrics.RemoveTokenActive(ctx, s.controllerName, s.key.String())
+		return reconcile.Result{RequeueAfter: s.owner.GetRefreshInterval()}, nil
+	}
+
+	return reconcile.Result{RequeueAfter: s.nextRefresh()}, nil
+}
+
+// fanOutTarget reports whether secret is the copy of the Secret for one of
+// the target namespaces, rather than a stale copy to delete.
+func (s *tokenSecret) fanOutTarget(secret corev1.Secret, targets []string) bool {
+	return secret.Name == s.owner.GetSecretName() && slices.Contains(targets, secret.Namespace)
+}
+
+// fanOutCondition returns the Ready condition for a fan-out owner whose
+// Secret is held by the written namespaces, recording any conflicts. With
+// neither, no namespace matches the owner's selector.
+func (s *tokenSecret) fanOutCondition(ctx context.Context, written, conflicts []string) *metav1.Condition {
+	if len(conflicts) > 0 {
+		s.metrics.RecordReconcileError(ctx, s.controllerName, metrics.ReasonOwnership)
+		s.log.Info("existing secrets not owned by token", "namespaces", conflicts)
+		message := "Secret already exists in namespaces: " + strings.Join(conflicts, ", ")
+		s.events.Warning(s.owner, events.ReasonOwnershipConflict, events.ActionUpdateSecret, message)
+		return &metav1.Condition{
+			Type:    githubv1.ConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Failed",
+			Message: message,
+		}
+	}
+	if len(written) == 0 {
+		return &metav1.Condition{
+			Type:    githubv1.ConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  githubv1.ReasonNoTargets,
+			Message: "No namespace matches namespaceSelector",
+		}
+	}
+	return &metav1.Condition{
+		Type:    githubv1.ConditionTypeReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Updated",
+		Message: fmt.Sprintf("Updated Secret in %d namespaces", len(written)),
+	}
+}
+
+// fanOutDueIn returns how long until the copies of the Secret in the target
+// namespaces are due for refresh, along with the target namespaces holding a
+// same-named Secret the owner does not control and the copies the owner
+// controls that have lost their managed labels, so are missing from existing.
+// A zero duration means at least one copy is missing, tampered with or due,
+// or the copies hold different tokens after a partly failed write, so a new
+// token is needed.
+func (s *tokenSecret) fanOutDueIn(ctx context.Context, targets []string, existing []corev1.Secret) (dueIn time.Duration, conflicts []string, unlabelled []corev1.Secret, err error) {
+	var checksum string
+	for _, namespace := range targets {
+		var secret *corev1.Secret
+		if i := slices.IndexFunc(existing, func(secret corev1.Secret) bool {
+			return secret.Namespace == namespace && secret.Name == s.owner.GetSecretName()
+		}); i >= 0 {
+			secret = &existing[i]
+		} else {
+			secret = &corev1.Secret{}
+			err := s.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: s.owner.GetSecretName()}, secret)
+			if apierrors.IsNotFound(err) {
+				return 0, nil, nil, nil
+			}
+			if err != nil {
+				return 0, nil, nil, err
+			}
+			if !metav1.IsControlledBy(secret, s.owner) {
+				conflicts = append(conflicts, namespace)
+				continue
+			}
+			// Owned but missing its managed labels: restored along with
+			// the rest of its metadata if the token is still fresh.
+			unlabelled = append(unlabelled, *secret)
+		}
+
+		copyDueIn := s.freshFor(secret)
+		if copyDueIn == 0 {
+			return 0, nil, nil, nil
+		}
+		if checksum == "" {
+			checksum = secret.Annotations[AnnotationChecksum]
+		} else if secret.Annotations[AnnotationChecksum] != checksum {
+			return 0, nil, nil, nil
+		}
+		if dueIn == 0 || copyDueIn < dueIn {
+			dueIn = copyDueIn
+		}
+	}
+	return dueIn, conflicts, unlabelled, nil
+}
+
+// targetNamespaces returns the sorted names of the active namespaces
+// matching the owner's namespace selector.
+func (s *tokenSecret) targetNamespaces(ctx context.Context, owner FanOutTokenManager) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(owner.GetSecretNamespaceSelector())
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespaceSelector: %w", err)
+	}
+	var list corev1.NamespaceList
+	if err := s.client.List(ctx, &list, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	namespaces := make([]string, 0, len(list.Items))
+	for _, namespace := range list.Items {
+		if namespace.Status.Phase == corev1.NamespaceTerminating || !namespace.DeletionTimestamp.IsZero() {
+			continue
+		}
+		namespaces = append(namespaces, namespace.Name)
+	}
+	slices.Sort(namespaces)
+	return namespaces, nil
+}
+
+// fanOutSecrets returns every Secret, in any namespace, controlled by the
+// owner.
+func (s *tokenSecret) fanOutSecrets(ctx context.Context) ([]corev1.Secret, error) {
+	var list corev1.SecretList
+	if err := s.client.List(ctx, &list, client.MatchingLabels{
+		"app.kubernetes.io/name":     s.owner.GetType(),
+		"app.kubernetes.io/instance": s.owner.GetName(),
+	}); err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(list.Items, func(secret corev1.Secret) bool {
+		return !metav1.IsControlledBy(&secret, s.owner)
+	}), nil
+}
+
+// deleteFanOutSecrets deletes every copy of the Secret written for a
+// namespace selector, used when the owner switches to a single namespace.
+func (s *tokenSecret) deleteFanOutSecrets(ctx context.Context) error {
+	existing, err := s.fanOutSecrets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, secret := range existing {
+		if err := s.deleteFanOutSecret(ctx, secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *tokenSecret) deleteFanOutSecret(ctx context.Context, secret corev1.Secret) error {
+	log := s.log.WithValues("func", "deleteFanOutSecret", "secret", client.ObjectKeyFromObject(&secret))
+
+	log.Info("deleting secret from namespace no longer targeted")
+	if err := s.client.Delete(ctx, &secret); err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "failed to delete secret")
+		s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationDelete, metrics.ResultError)
+		return err
+	}
+	s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationDelete, metrics.ResultSuccess)
+	s.events.Normal(s.owner, events.ReasonSecretDeleted, events.ActionDeleteSecret,
+		"Deleted old Secret "+client.ObjectKeyFromObject(&secret).String())
+	return nil
+}
+
+// writeFanOutSecrets mints one installation token and creates or updates the
+// Secret in each target namespace. Namespaces holding a same-named Secret the
+// owner does not control are skipped and returned as conflicts.
+func (s *tokenSecret) writeFanOutSecrets(ctx context.Context, targets []string) (installationToken *github.InstallationToken, conflicts []string, err error) {
+	log := s.log.WithValues("func", "writeFanOutSecrets")
+
+	if len(targets) == 0 {
+		return nil, nil, nil
+	}
+
+	parsed, err := ParseSecretTemplate(s.owner.GetSecretTemplate())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	installationToken, err = s.NewInstallationToken(ctx)
+	if err != nil {
+		log.Error(err, "failed to get installation token")
+		return nil, nil, err
+	}
+
+	data, err := s.SecretData(installationToken, parsed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	annotations := s.SecretAnnotations(data, installationToken.GetExpiresAt().Time)
+
+	var (
+		previousTokens []string
+		rotated        int
+		written        int
+		// Tokens still held by copies that failed to be written, which must
+		// not be revoked; unknown if one could not even be read.
+		held    []string
+		unknown bool
+		errs    []error
+	)
+	for _, namespace := range targets {
+		key := types.NamespacedName{Namespace: namespace, Name: s.owner.GetSecretName()}
+		previousToken, created, conflict, err := s.writeFanOutSecret(ctx, key, data, annotations)
+		if err != nil {
+			// Write the remaining copies regardless, so that as few as
+			// possible are left holding the outgoing token.
+			errs = append(errs, err)
+			if previousToken != "" {
+				held = append(held, previousToken)
+			} else if !created {
+				unknown = true
+			}
+			continue
+		}
+		if conflict {
+			conflicts = append(conflicts, namespace)
+			continue
+		}
+		written++
+		if !created {
+			rotated++
+		}
+		if previousToken != "" && previousToken != installationToken.GetToken() && !slices.Contains(previousTokens, previousToken) {
+			previousTokens = append(previousTokens, previousToken)
+		}
+	}
+
+	if rotated > 0 {
+		// A single event covers every copy, so that rotation is no noisier
+		// for a namespace selector than for a single Secret.
+		s.events.Normal(s.owner, events.ReasonSecretRotated, events.ActionUpdateSecret,
+			fmt.Sprintf("Rotated the token in Secret %s in %d namespaces", s.owner.GetSecretName(), rotated))
+	}
+
+	if s.owner.GetRevoke() && !unknown {
+		// A token still held by a copy that failed to be written stays live
+		// until the next reconcile, which finds the copies out of step and
+		// rewrites them all.
+		for _, previousToken := range previousTokens {
+			if !slices.Contains(held, previousToken) {
+				s.revokeToken(ctx, previousToken)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		if s.owner.GetRevoke() && written == 0 {
+			s.revokeToken(ctx, installationToken.GetToken())
+		}
+		return nil, nil, errors.Join(errs...)
+	}
+
+	return installationToken, conflicts, nil
+}
+
+// writeFanOutSecret creates or updates a single copy of the Secret, returning
+// the installation token it held before the update, even if the update
+// failed, and whether it was created, or would have been.
+func (s *tokenSecret) writeFanOutSecret(ctx context.Context, key types.NamespacedName, data map[string][]byte, annotations map[string]string) (previousToken string, created, conflict bool, err error) {
+	log := s.log.WithValues("func", "writeFanOutSecret", "secret", key)
+
+	secret := &corev1.Secret{}
+	err = s.client.Get(ctx, key, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "failed to get secret")
+		return "", false, false, err
+	}
+
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   key.Namespace,
+				Name:        key.Name,
+				Annotations: maps.Clone(annotations),
+			},
+			Data: data,
+			Type: s.owner.GetSecretType(),
+		}
+		s.applyMetadata(secret)
+		if err := ctrl.SetControllerReference(s.owner, secret, s.client.Scheme()); err != nil {
+			log.Error(err, "failed to set controller reference")
+			return "", true, false, err
+		}
+		if err := s.client.Create(ctx, secret); err != nil {
+			log.Error(err, "failed to create secret")
+			s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationCreate, metrics.ResultError)
+			return "", true, false, err
+		}
+		s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationCreate, metrics.ResultSuccess)
+		s.events.Normal(s.owner, events.ReasonSecretCreated, events.ActionCreateSecret, "Created Secret "+key.String())
+		return "", true, false, nil
+	}
+
+	if !metav1.IsControlledBy(secret, s.owner) {
+		return "", false, true, nil
+	}
+
+	previousToken = outgoingToken(secret)
+	secret.Data = data
+	s.applyMetadata(secret)
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	maps.Copy(secret.Annotations, annotations)
+	if err := s.client.Update(ctx, secret); err != nil {
+		log.Error(err, "failed to update secret")
+		s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationUpdate, metrics.ResultError)
+		return previousToken, false, false, err
+	}
+	s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationUpdate, metrics.ResultSuccess)
+	return previousToken, false, false, nil
+}
+
+// updateFanOutStatus is [tokenSecret.UpdateTokenStatus] for fan-out owners,
+// additionally recording the namespaces that hold a copy of the Secret. A
+// Ready=True condition resets the count of failed reconciles.
+func (s *tokenSecret) updateFanOutStatus(ctx context.Context, owner FanOutTokenManager, condition *metav1.Condition, installationToken *github.InstallationToken, namespaces []string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := s.RefreshOwner(ctx); err != nil {
+			return err
+		}
+
+		changed := owner.SetStatusCondition(*condition)
+		if condition.Status == metav1.ConditionTrue && owner.GetStatusFailures().ResetFailures() {
+			changed = true
+		}
+		if installationToken != nil {
+			owner.SetStatusTimestamps(installationToken.GetExpiresAt().Time)
+			owner.SetStatusGrant(installationToken)
+			owner.SetStatusSelectedRepositories(s.repositories)
+			changed = true
+		}
+		if owner.UpdateManagedSecret() {
+			changed = true
+		}
+		if owner.SetStatusTargetNamespaces(namespaces) {
+			changed = true
+		}
+
+		if !changed {
+			return nil
+		}
+		return s.client.Status().Update(ctx, owner)
+	})
+}
@@ -0,0 +1,298 @@
+package tokenmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+)
+
+// fakeGHAIT mints a fixed installation token, counting mints and recording
+// the options of the last.
+type fakeGHAIT struct {
+	mints   int
+	options *github.InstallationTokenOptions
+}
+
+func (*fakeGHAIT) GetAppID() int64          { return 1 }
+func (*fakeGHAIT) GetInstallationID() int64 { return 2 }
+func (f *fakeGHAIT) NewInstallationToken(_ context.Context, _ int64, options *github.InstallationTokenOptions) (*github.InstallationToken, error) {
+	f.mints++
+	f.options = options
+	return &github.InstallationToken{
+		Token:     github.Ptr("ghs_test"),
+		ExpiresAt: &github.Timestamp{Time: time.Now().Add(time.Hour)},
+	}, nil
+}
+func (f *fakeGHAIT) NewToken(ctx context.Context) (*github.InstallationToken, error) {
+	return f.NewInstallationToken(ctx, 0, nil)
+}
+func (f *fakeGHAIT) NewTokenWithOptions(ctx context.Context, opts *github.InstallationTokenOptions) (*github.InstallationToken, error) {
+	return f.NewInstallationToken(ctx, 0, opts)
+}
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := githubv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func namespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestReconcileFanOut(t *testing.T) {
+	scheme := testScheme(t)
+
+	token := &githubv1.ClusterToken{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared", UID: "uid-shared"},
+		Spec: githubv1.ClusterTokenSpec{
+			Secret: githubv1.ClusterTokenSecretSpec{
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"tenant": "true"},
+				},
+			},
+		},
+	}
+	unowned := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-c", Name: "shared"}}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			token,
+			namespace("team-a", map[string]string{"tenant": "true"}),
+			namespace("team-b", map[string]string{"tenant": "true"}),
+			namespace("team-c", map[string]string{"tenant": "true"}),
+			namespace("other", nil),
+			unowned,
+		).
+		WithStatusSubresource(token).
+		Build()
+
+	ctx := context.Background()
+	key := types.NamespacedName{Name: token.Name}
+	owner := &githubv1.ClusterToken{}
+	if err := c.Get(ctx, key, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(&fakeGHAIT{}))
+	if _, err := s.Reconcile(ctx); err != nil {
+		t.Fatalf("Reconcile() err = %v", err)
+	}
+
+	for _, ns := range []string{"team-a", "team-b"} {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: ns, Name: "shared"}, secret); err != nil {
+			t.Fatalf("secret in %s: %v", ns, err)
+		}
+		if got := string(secret.Data["token"]); got != "ghs_test" {
+			t.Errorf("secret in %s token = %q, want ghs_test", ns, got)
+		}
+	}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "other", Name: "shared"}, &corev1.Secret{}); err == nil {
+		t.Error("secret written to non-matching namespace")
+	}
+
+	if err := c.Get(ctx, key, owner); err != nil {
+		t.Fatal(err)
+	}
+	if got := owner.GetStatusTargetNamespaces(); len(got) != 2 || got[0] != "team-a" || got[1] != "team-b" {
+		t.Errorf("TargetNamespaces = %v, want [team-a team-b]", got)
+	}
+	for _, cond := range owner.GetStatusConditions() {
+		if cond.Type == githubv1.ConditionTypeReady && cond.Status != metav1.ConditionFalse {
+			t.Errorf("Ready = %s, want False for unowned secret in team-c", cond.Status)
+		}
+	}
+
+	// Unlabel team-b: its copy is removed on the next reconcile.
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: "team-b"}, ns); err != nil {
+		t.Fatal(err)
+	}
+	ns.Labels = nil
+	if err := c.Update(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	s = NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(&fakeGHAIT{}))
+	if _, err := s.Reconcile(ctx); err != nil {
+		t.Fatalf("second Reconcile() err = %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "team-b", Name: "shared"}, &corev1.Secret{}); err == nil {
+		t.Error("secret not removed from namespace that no longer matches")
+	}
+}
+
+func TestReconcileFanOut_RestoresLabels(t *testing.T) {
+	token := &githubv1.ClusterToken{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared", UID: "uid-shared"},
+		Spec: githubv1.ClusterTokenSpec{
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+			Secret: githubv1.ClusterTokenSecretSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(testScheme(t)).
+		WithObjects(token, namespace("team-a", map[string]string{"tenant": "true"}), namespace("team-b", map[string]string{"tenant": "true"})).
+		WithStatusSubresource(token).
+		Build()
+	ctx := context.Background()
+	key := types.NamespacedName{Name: token.Name}
+	reconcile := func(mints *fakeGHAIT) {
+		t.Helper()
+		owner := &githubv1.ClusterToken{}
+		if err := c.Get(ctx, key, owner); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(mints)).Reconcile(ctx); err != nil {
+			t.Fatalf("Reconcile() err = %v", err)
+		}
+	}
+	reconcile(&fakeGHAIT{})
+
+	secretKey := types.NamespacedName{Namespace: "team-b", Name: "shared"}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, secretKey, secret); err != nil {
+		t.Fatal(err)
+	}
+	secret.Labels = nil
+	if err := c.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+
+	mints := &fakeGHAIT{}
+	reconcile(mints)
+	if mints.mints != 0 {
+		t.Errorf("mints = %d, want 0 for a copy that only lost its labels", mints.mints)
+	}
+	if err := c.Get(ctx, secretKey, secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.Labels["app.kubernetes.io/instance"] != "shared" {
+		t.Errorf("labels = %v, want managed labels restored", secret.Labels)
+	}
+}
+
+func TestReconcileFanOut_DeletedCopyNotSynced(t *testing.T) {
+	token := &githubv1.ClusterToken{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared", UID: "uid-shared"},
+		Spec: githubv1.ClusterTokenSpec{
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+			Secret: githubv1.ClusterTokenSecretSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(testScheme(t)).
+		WithObjects(token, namespace("team-a", map[string]string{"tenant": "true"}), namespace("team-b", map[string]string{"tenant": "true"})).
+		WithStatusSubresource(token).
+		Build()
+	ctx := context.Background()
+	key := types.NamespacedName{Name: token.Name}
+	owner := &githubv1.ClusterToken{}
+	if err := c.Get(ctx, key, owner); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(&fakeGHAIT{})).Reconcile(ctx); err != nil {
+		t.Fatalf("Reconcile() err = %v", err)
+	}
+
+	// Unlabel team-b while changing the managed labels: only team-a's copy
+	// is left to sync.
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: "team-b"}, ns); err != nil {
+		t.Fatal(err)
+	}
+	ns.Labels = nil
+	if err := c.Update(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, key, owner); err != nil {
+		t.Fatal(err)
+	}
+	owner.Spec.Secret.Labels = map[string]string{"team": "platform"}
+	mints := &fakeGHAIT{}
+	if _, err := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(mints)).Reconcile(ctx); err != nil {
+		t.Fatalf("second Reconcile() err = %v", err)
+	}
+	if mints.mints != 0 {
+		t.Errorf("mints = %d, want 0", mints.mints)
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "shared"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.Labels["team"] != "platform" {
+		t.Errorf("team-a labels = %v, want team=platform", secret.Labels)
+	}
+}
+
+func TestReconcileFanOut_NoWritableTargets(t *testing.T) {
+	tests := []struct {
+		name       string
+		objects    []client.Object
+		wantReason string
+	}{
+		{name: "no matching namespace", objects: []client.Object{namespace("other", nil)}, wantReason: githubv1.ReasonNoTargets},
+		{name: "every namespace conflicting", objects: []client.Object{
+			namespace("team-a", map[string]string{"tenant": "true"}),
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "shared"}},
+		}, wantReason: "Failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &githubv1.ClusterToken{
+				ObjectMeta: metav1.ObjectMeta{Name: "shared", UID: "uid-shared"},
+				Spec: githubv1.ClusterTokenSpec{
+					RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+					Secret: githubv1.ClusterTokenSecretSpec{
+						NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+					},
+				},
+			}
+			c := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(append(tt.objects, token)...).WithStatusSubresource(token).Build()
+			ctx := context.Background()
+			key := types.NamespacedName{Name: token.Name}
+			owner := &githubv1.ClusterToken{}
+			if err := c.Get(ctx, key, owner); err != nil {
+				t.Fatal(err)
+			}
+
+			gh := &fakeGHAIT{}
+			if _, err := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(gh)).Reconcile(ctx); err != nil {
+				t.Fatalf("Reconcile() err = %v", err)
+			}
+			if gh.mints != 0 {
+				t.Errorf("mints = %d, want 0 without a namespace to write to", gh.mints)
+			}
+			if err := c.Get(ctx, key, owner); err != nil {
+				t.Fatal(err)
+			}
+			ready := meta.FindStatusCondition(owner.GetStatusConditions(), githubv1.ConditionTypeReady)
+			if ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != tt.wantReason {
+				t.Errorf("Ready = %+v, want False with reason %s", ready, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package tokenmanager
+
+import (
+	"fmt"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+)
+
+// gitCredentialFiles returns the requested git credential files for host,
+// keyed by their Secret data key.
+func gitCredentialFiles(formats []githubv1.GitFormat, host, installationToken string) map[string][]byte {
+	files := make(map[string][]byte, len(formats))
+	for _, format := range formats {
+		var content string
+		switch format {
+		case githubv1.GitFormatNetrc:
+			content = fmt.Sprintf("machine %s\nlogin %s\npassword %s\n", host, BasicAuthUsername, installationToken)
+		case githubv1.GitFormatGitCredentials:
+			content = fmt.Sprintf("https://%s:%s@%s\n", BasicAuthUsername, installationToken, host)
+		case githubv1.GitFormatGitconfig:
+			content = fmt.Sprintf("[url \"https://%s:%s@%s/\"]\n"+
+				"\tinsteadOf = https://%s/\n"+
+				"\tinsteadOf = ssh://git@%s/\n"+
+				"\tinsteadOf = git@%s:\n",
+				BasicAuthUsername, installationToken, host, host, host, host)
+		default:
+			continue
+		}
+		files[format.DataKey()] = []byte(content)
+	}
+	return files
+}
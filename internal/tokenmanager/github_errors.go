@@ -0,0 +1,199 @@
+package tokenmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/events"
+	"github.com/isometry/github-token-manager/internal/ghapp"
+	"github.com/isometry/github-token-manager/internal/metrics"
+)
+
+const (
+	// installationNotFoundRetryInterval is how long to wait before minting
+	// again for an installation GitHub reports missing, giving an
+	// uninstalled App time to be reinstalled.
+	installationNotFoundRetryInterval = 15 * time.Minute
+	// invalidRepositoriesRetryInterval is how long to wait before minting
+	// again after GitHub rejected the requested repositories. Repositories
+	// are created and added to installations outside the cluster, but
+	// rarely, so there is no point in asking often.
+	invalidRepositoriesRetryInterval = 30 * time.Minute
+	// permissionsRejectedRetryInterval is how long to wait before minting
+	// again after GitHub refused the requested permissions, matching how
+	// often an App's installations are refreshed.
+	permissionsRejectedRetryInterval = 15 * time.Minute
+	// rateLimitRetryInterval is how long to wait before minting again after
+	// hitting a rate limit that GitHub gave no reset or Retry-After for.
+	rateLimitRetryInterval = time.Minute
+)
+
+// githubFailure is a failed GitHub API call classified by its cause.
+type githubFailure struct {
+	// reason is the Ready condition reason reported for the failure.
+	reason string
+	// metric is the reason label the reconcile error is recorded with.
+	metric string
+	// message describes the failure in the Ready condition and events.
+	message string
+	// retryAfter is the least time to wait before minting again, however
+	// few failures in a row the owner has backed off for.
+	retryAfter time.Duration
+	// exact is set when retryAfter is when a rate limit lifts, so that the
+	// mint is retried then rather than backing off any further.
+	exact bool
+	err   error
+}
+
+// classifyGitHubError classifies a failure to mint a token for
+// installationID, or returns nil if err is not a GitHub API error the
+// operator recognises. Credentials GitHub rejects are retried as the owner
+// backs off, as a rotated key is picked up by the App without any change to
+// the owner.
+func classifyGitHubError(err error, installationID int64) *githubFailure {
+	if limitErr := (*ghapp.RateLimitedError)(nil); errors.As(err, &limitErr) {
+		// Deferred before reaching GitHub, so retried exactly when the
+		// budget allows.
+		return &githubFailure{
+			reason:     githubv1.ReasonRateLimited,
+			metric:     metrics.ReasonRateLimited,
+			message:    "GitHub App's API rate limit budget is low, deferring the mint until it recovers",
+			retryAfter: max(limitErr.RetryAfter, time.Second),
+			exact:      true,
+			err:        err,
+		}
+	}
+	if rateErr := (*github.RateLimitError)(nil); errors.As(err, &rateErr) {
+		reset := rateErr.Rate.Reset.Time
+		return &githubFailure{
+			reason:     githubv1.ReasonRateLimited,
+			metric:     metrics.ReasonRateLimited,
+			message:    "GitHub API rate limit exhausted until " + reset.UTC().Format(time.RFC3339),
+			retryAfter: rateLimitDelay(time.Until(reset)),
+			exact:      true,
+			err:        err,
+		}
+	}
+	if abuseErr := (*github.AbuseRateLimitError)(nil); errors.As(err, &abuseErr) {
+		var retryAfter time.Duration
+		if abuseErr.RetryAfter != nil {
+			retryAfter = *abuseErr.RetryAfter
+		}
+		return rateLimited(retryAfter, err)
+	}
+
+	ghErr := (*github.ErrorResponse)(nil)
+	if !errors.As(err, &ghErr) || ghErr.Response == nil {
+		return nil
+	}
+	switch ghErr.Response.StatusCode {
+	case http.StatusUnauthorized:
+		return &githubFailure{
+			reason:  githubv1.ReasonAuthenticationFailed,
+			metric:  metrics.ReasonAuthenticationFailed,
+			message: "GitHub rejected the GitHub App's credentials, check that its private key has not been revoked: " + ghErr.Message,
+			err:     err,
+		}
+	case http.StatusNotFound:
+		return &githubFailure{
+			reason:     githubv1.ReasonInstallationNotFound,
+			metric:     metrics.ReasonInstallationNotFound,
+			message:    fmt.Sprintf("Installation %d of the GitHub App not found, it may have been uninstalled", installationID),
+			retryAfter: installationNotFoundRetryInterval,
+			err:        err,
+		}
+	case http.StatusTooManyRequests:
+		return rateLimited(retryAfterHeader(ghErr.Response), err)
+	case http.StatusForbidden:
+		if retryAfter := retryAfterHeader(ghErr.Response); retryAfter > 0 {
+			return rateLimited(retryAfter, err)
+		}
+		return permissionsRejected(ghErr.Message, err)
+	case http.StatusUnprocessableEntity:
+		if strings.Contains(strings.ToLower(ghErr.Message), "permission") {
+			return permissionsRejected(ghErr.Message, err)
+		}
+		return &githubFailure{
+			reason:     githubv1.ReasonInvalidRepositories,
+			metric:     metrics.ReasonInvalidRepositories,
+			message:    "GitHub rejected the requested repositories, check that each exists and is accessible to the installation: " + ghErr.Message,
+			retryAfter: invalidRepositoriesRetryInterval,
+			err:        err,
+		}
+	}
+	return nil
+}
+
+func permissionsRejected(message string, err error) *githubFailure {
+	return &githubFailure{
+		reason:     githubv1.ReasonPermissionsRejected,
+		metric:     metrics.ReasonPermissionsRejected,
+		message:    "GitHub refused to grant the requested permissions, check that the installation has been granted them: " + message,
+		retryAfter: permissionsRejectedRetryInterval,
+		err:        err,
+	}
+}
+
+func rateLimited(retryAfter time.Duration, err error) *githubFailure {
+	return &githubFailure{
+		reason:     githubv1.ReasonRateLimited,
+		metric:     metrics.ReasonRateLimited,
+		message:    "GitHub API rate limit exceeded, the GitHub App is making too many requests",
+		retryAfter: rateLimitDelay(retryAfter),
+		exact:      true,
+		err:        err,
+	}
+}
+
+// rateLimitDelay returns how long to wait for a rate limit to lift, given
+// how long GitHub asked for plus a second's margin for clock skew, falling
+// back to rateLimitRetryInterval.
+func rateLimitDelay(retryAfter time.Duration) time.Duration {
+	if retryAfter <= 0 {
+		return rateLimitRetryInterval
+	}
+	return retryAfter.Round(time.Second) + time.Second
+}
+
+// retryAfterHeader returns the delay in seconds given by the Retry-After
+// header of response, or zero if it has none.
+func retryAfterHeader(response *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(response.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// githubFailed surfaces a classified GitHub API failure as a Ready=False
+// condition, and requeues the owner once it has backed off, no sooner than
+// the failure's class dictates.
+func (s *tokenSecret) githubFailed(ctx context.Context, failure *githubFailure) (reconcile.Result, error) {
+	log := s.log.WithValues("func", "githubFailed")
+
+	s.metrics.RecordReconcileError(ctx, s.controllerName, failure.metric)
+	s.events.Warning(s.owner, failure.reason, events.ActionMintToken, failure.message)
+	log.Error(failure.err, "GitHub rejected token request", "reason", failure.reason, "retryAfter", failure.retryAfter)
+
+	condition := metav1.Condition{
+		Type:    githubv1.ConditionTypeReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  failure.reason,
+		Message: failure.message,
+	}
+	result, err := s.failed(ctx, &condition, failure.retryAfter)
+	if err == nil && failure.exact {
+		result.RequeueAfter = failure.retryAfter
+	}
+	return result, err
+}
@@ -0,0 +1,142 @@
+package tokenmanager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+
+	"github.com/isometry/ghait/v84"
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/ghapp"
+	"github.com/isometry/github-token-manager/internal/metrics"
+)
+
+func TestClassifyGitHubError(t *testing.T) {
+	response := func(status int, header ...string) *http.Response {
+		r := &http.Response{StatusCode: status, Header: http.Header{}, Request: &http.Request{Method: http.MethodPost}}
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		return r
+	}
+	errorResponse := func(status int, message string, header ...string) error {
+		return fmt.Errorf("mint: %w", &github.ErrorResponse{Response: response(status, header...), Message: message})
+	}
+	secondary := 42 * time.Second
+
+	tests := []struct {
+		name       string
+		err        error
+		wantReason string
+		wantMetric string
+		wantRetry  time.Duration
+	}{
+		{
+			name:       "revoked key",
+			err:        errorResponse(http.StatusUnauthorized, "A JSON web token could not be decoded"),
+			wantReason: githubv1.ReasonAuthenticationFailed,
+			wantMetric: metrics.ReasonAuthenticationFailed,
+		},
+		{
+			name:       "missing installation",
+			err:        errorResponse(http.StatusNotFound, "Not Found"),
+			wantReason: githubv1.ReasonInstallationNotFound,
+			wantMetric: metrics.ReasonInstallationNotFound,
+			wantRetry:  installationNotFoundRetryInterval,
+		},
+		{
+			name:       "bad repository name",
+			err:        errorResponse(http.StatusUnprocessableEntity, "There is at least one repository that does not exist or is not accessible to the parent installation."),
+			wantReason: githubv1.ReasonInvalidRepositories,
+			wantMetric: metrics.ReasonInvalidRepositories,
+			wantRetry:  invalidRepositoriesRetryInterval,
+		},
+		{
+			name:       "permissions not granted",
+			err:        errorResponse(http.StatusUnprocessableEntity, "The permissions requested are not granted to this installation."),
+			wantReason: githubv1.ReasonPermissionsRejected,
+			wantMetric: metrics.ReasonPermissionsRejected,
+			wantRetry:  permissionsRejectedRetryInterval,
+		},
+		{
+			name:       "forbidden",
+			err:        errorResponse(http.StatusForbidden, "Resource not accessible by integration"),
+			wantReason: githubv1.ReasonPermissionsRejected,
+			wantMetric: metrics.ReasonPermissionsRejected,
+			wantRetry:  permissionsRejectedRetryInterval,
+		},
+		{
+			name:       "forbidden with Retry-After",
+			err:        errorResponse(http.StatusForbidden, "You have exceeded a secondary rate limit", "Retry-After", "30"),
+			wantReason: githubv1.ReasonRateLimited,
+			wantMetric: metrics.ReasonRateLimited,
+			wantRetry:  31 * time.Second,
+		},
+		{
+			name:       "too many requests without Retry-After",
+			err:        errorResponse(http.StatusTooManyRequests, "Too Many Requests"),
+			wantReason: githubv1.ReasonRateLimited,
+			wantMetric: metrics.ReasonRateLimited,
+			wantRetry:  rateLimitRetryInterval,
+		},
+		{
+			name:       "secondary rate limit",
+			err:        ghait.TransientError{Err: &github.AbuseRateLimitError{Response: response(http.StatusForbidden), RetryAfter: &secondary}},
+			wantReason: githubv1.ReasonRateLimited,
+			wantMetric: metrics.ReasonRateLimited,
+			wantRetry:  43 * time.Second,
+		},
+		{
+			name:       "low rate limit budget",
+			err:        fmt.Errorf("mint: %w", &ghapp.RateLimitedError{RetryAfter: 6 * time.Second, Remaining: 100}),
+			wantReason: githubv1.ReasonRateLimited,
+			wantMetric: metrics.ReasonRateLimited,
+			wantRetry:  6 * time.Second,
+		},
+		{
+			name: "server error",
+			err:  errorResponse(http.StatusBadGateway, "Bad Gateway"),
+		},
+		{
+			name: "not a GitHub error",
+			err:  errors.New("connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := classifyGitHubError(tt.err, 2)
+			if tt.wantReason == "" {
+				if failure != nil {
+					t.Fatalf("classifyGitHubError() = %+v, want nil", failure)
+				}
+				return
+			}
+			if failure == nil {
+				t.Fatal("classifyGitHubError() = nil, want a failure")
+			}
+			if failure.reason != tt.wantReason || failure.metric != tt.wantMetric || failure.retryAfter != tt.wantRetry {
+				t.Errorf("classifyGitHubError() = (%s, %s, %v), want (%s, %s, %v)",
+					failure.reason, failure.metric, failure.retryAfter, tt.wantReason, tt.wantMetric, tt.wantRetry)
+			}
+			if want := tt.wantReason == githubv1.ReasonRateLimited; failure.exact != want {
+				t.Errorf("classifyGitHubError() exact = %v, want %v", failure.exact, want)
+			}
+			if failure.message == "" {
+				t.Error("classifyGitHubError() message is empty")
+			}
+		})
+	}
+
+	reset := time.Now().Add(10 * time.Minute)
+	failure := classifyGitHubError(&github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: reset}}, Response: response(http.StatusForbidden)}, 2)
+	if failure == nil || failure.reason != githubv1.ReasonRateLimited {
+		t.Fatalf("classifyGitHubError(RateLimitError) = %+v, want RateLimited", failure)
+	}
+	if failure.retryAfter < 9*time.Minute || failure.retryAfter > 11*time.Minute {
+		t.Errorf("retryAfter = %v, want until the rate limit resets", failure.retryAfter)
+	}
+}
@@ -0,0 +1,170 @@
+package tokenmanager
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/metrics"
+)
+
+// RevokeFinalizer holds a Token or ClusterToken with spec.revoke set until
+// its live installation token has been revoked.
+const RevokeFinalizer = "github.as-code.io/revoke-token"
+
+// RevokeFunc revokes the installation token it is given.
+type RevokeFunc func(ctx context.Context, token string) error
+
+// WithRevoker overrides how installation tokens are revoked.
+func WithRevoker(revoke RevokeFunc) Option {
+	return func(s *tokenSecret) {
+		s.revoke = revoke
+	}
+}
+
+// WithGitHubClient sets the unauthenticated go-github client, configured for
+// the App's endpoint, CA bundle and proxy, that installation tokens are
+// revoked through. Defaults to a github.com client.
+func WithGitHubClient(gh *github.Client) Option {
+	return func(s *tokenSecret) {
+		s.github = gh
+	}
+}
+
+// newRevoker returns a RevokeFunc that calls DELETE /installation/token
+// through gh, authenticated as the token being revoked.
+func newRevoker(gh *github.Client) RevokeFunc {
+	return func(ctx context.Context, token string) error {
+		_, err := gh.WithAuthToken(token).Apps.RevokeInstallationToken(ctx)
+		return err
+	}
+}
+
+// revokeToken revokes an outgoing installation token. Failures are logged and
+// counted but never returned: the token expires on its own within the hour,
+// so revocation must not hold up rotation or deletion.
+func (s *tokenSecret) revokeToken(ctx context.Context, token string) {
+	log := s.log.WithValues("func", "revokeToken")
+
+	if token == "" {
+		log.Info("no outgoing token found to revoke")
+		return
+	}
+
+	start := time.Now()
+	err := s.revoke(ctx, token)
+	s.metrics.RecordGitHubAPICall(ctx, s.controllerName, time.Since(start), err)
+	if err != nil {
+		log.Error(err, "failed to revoke installation token")
+		s.metrics.RecordTokenRevocation(ctx, s.controllerName, metrics.ResultError)
+		return
+	}
+	log.V(1).Info("revoked installation token")
+	s.metrics.RecordTokenRevocation(ctx, s.controllerName, metrics.ResultSuccess)
+}
+
+// outgoingToken recovers the installation token from the data of a managed
+// Secret. Templated Secrets are opaque to the operator, so an empty string is
+// returned for them; spec.revoke cannot be set along with a template.
+func outgoingToken(secret *corev1.Secret) string {
+	if secret == nil {
+		return ""
+	}
+	if token, ok := secret.Data["token"]; ok {
+		return string(token)
+	}
+	if password, ok := secret.Data["password"]; ok {
+		return string(password)
+	}
+	if config, ok := secret.Data[corev1.DockerConfigJsonKey]; ok {
+		var parsed struct {
+			Auths map[string]dockerAuth `json:"auths"`
+		}
+		if err := json.Unmarshal(config, &parsed); err == nil {
+			return parsed.Auths[githubv1.DefaultDockerRegistry].Password
+		}
+	}
+	return ""
+}
+
+// EnsureFinalizer adds the revocation finalizer to the owner when spec.revoke
+// is set, and removes it when it is not.
+func (s *tokenSecret) EnsureFinalizer(ctx context.Context) error {
+	var changed bool
+	if s.owner.GetRevoke() {
+		changed = controllerutil.AddFinalizer(s.owner, RevokeFinalizer)
+	} else {
+		changed = controllerutil.RemoveFinalizer(s.owner, RevokeFinalizer)
+	}
+	if !changed {
+		return nil
+	}
+	return s.client.Update(ctx, s.owner)
+}
+
+// Finalize revokes the live installation token of an owner being deleted and
+// releases the revocation finalizer. The managed Secrets themselves are left
+// to garbage collection.
+func (s *tokenSecret) Finalize(ctx context.Context) error {
+	log := s.log.WithValues("func", "Finalize")
+
+	if !controllerutil.ContainsFinalizer(s.owner, RevokeFinalizer) {
+		return nil
+	}
+
+	token, err := s.liveToken(ctx)
+	if err != nil {
+		log.Error(err, "failed to read managed secret")
+		return err
+	}
+	s.revokeToken(ctx, token)
+
+	controllerutil.RemoveFinalizer(s.owner, RevokeFinalizer)
+	if err := s.client.Update(ctx, s.owner); err != nil {
+		log.Error(err, "failed to remove finalizer")
+		return err
+	}
+	s.metrics.RemoveTokenActive(ctx, s.controllerName, s.key.String())
+	return nil
+}
+
+// liveToken returns the installation token currently held by the owner's
+// managed Secret, or by any of its fanned-out copies.
+func (s *tokenSecret) liveToken(ctx context.Context) (string, error) {
+	managedSecret := s.owner.GetManagedSecret()
+	if managedSecret.IsUnset() {
+		return "", nil
+	}
+
+	if managedSecret.Namespace == "" {
+		secrets, err := s.fanOutSecrets(ctx)
+		if err != nil {
+			return "", err
+		}
+		for i := range secrets {
+			if token := outgoingToken(&secrets[i]); token != "" {
+				return token, nil
+			}
+		}
+		return "", nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := s.client.Get(ctx, managedSecret.Key(), secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !metav1.IsControlledBy(secret, s.owner) {
+		return "", nil
+	}
+	return outgoingToken(secret), nil
+}
@@ -0,0 +1,238 @@
+package tokenmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+)
+
+func TestOutgoingToken(t *testing.T) {
+	docker, err := dockerConfigJSON([]string{githubv1.DefaultDockerRegistry}, "ghs_docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		secret *corev1.Secret
+		want   string
+	}{
+		{name: "nil secret", secret: nil, want: ""},
+		{name: "token", secret: &corev1.Secret{Data: map[string][]byte{"token": []byte("ghs_token")}}, want: "ghs_token"},
+		{name: "basic auth", secret: &corev1.Secret{Data: map[string][]byte{"username": []byte(BasicAuthUsername), "password": []byte("ghs_basic")}}, want: "ghs_basic"},
+		{name: "docker config", secret: &corev1.Secret{Data: map[string][]byte{corev1.DockerConfigJsonKey: docker}}, want: "ghs_docker"},
+		{name: "template", secret: &corev1.Secret{Data: map[string][]byte{".npmrc": []byte("_authToken=ghs_template")}}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outgoingToken(tt.secret); got != tt.want {
+				t.Errorf("outgoingToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordingRevoker records revoked tokens, failing with err if set.
+type recordingRevoker struct {
+	revoked []string
+	err     error
+}
+
+func (r *recordingRevoker) revoke(_ context.Context, token string) error {
+	r.revoked = append(r.revoked, token)
+	return r.err
+}
+
+func revokingToken() *githubv1.Token {
+	return &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "revoking", UID: "uid-revoking"},
+		Spec: githubv1.TokenSpec{
+			Revoke:          true,
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+		},
+	}
+}
+
+func ownedSecret(t *testing.T, c client.Client, owner *githubv1.Token, token string) *corev1.Secret {
+	t.Helper()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: owner.Namespace, Name: owner.GetSecretName()},
+		Data:       map[string][]byte{"token": []byte(token)},
+		Type:       SecretTypeToken,
+	}
+	if err := controllerutil.SetControllerReference(owner, secret, c.Scheme()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	return secret
+}
+
+func TestReconcile_RevokesOutgoingToken(t *testing.T) {
+	for _, revokeErr := range []error{nil, errors.New("boom")} {
+		token := revokingToken()
+		c := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(token).WithStatusSubresource(token).Build()
+		ctx := context.Background()
+		key := client.ObjectKeyFromObject(token)
+
+		owner := &githubv1.Token{}
+		if err := c.Get(ctx, key, owner); err != nil {
+			t.Fatal(err)
+		}
+		ownedSecret(t, c, owner, "ghs_old")
+
+		revoker := &recordingRevoker{err: revokeErr}
+		s := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(&fakeGHAIT{}), WithRevoker(revoker.revoke))
+		if _, err := s.Reconcile(ctx); err != nil {
+			t.Fatalf("Reconcile() err = %v; revocation errors must not block rotation", err)
+		}
+
+		if len(revoker.revoked) != 1 || revoker.revoked[0] != "ghs_old" {
+			t.Errorf("revoked = %v, want [ghs_old]", revoker.revoked)
+		}
+
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "revoking"}, secret); err != nil {
+			t.Fatal(err)
+		}
+		if got := string(secret.Data["token"]); got != "ghs_test" {
+			t.Errorf("secret token = %q, want ghs_test", got)
+		}
+		if !controllerutil.ContainsFinalizer(owner, RevokeFinalizer) {
+			t.Error("revoke finalizer not added")
+		}
+	}
+}
+
+func TestFinalize_RevokesLiveToken(t *testing.T) {
+	token := revokingToken()
+	token.Finalizers = []string{RevokeFinalizer}
+	token.Status.ManagedSecret = githubv1.ManagedSecret{Namespace: "default", Name: "revoking"}
+	c := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(token).Build()
+	ctx := context.Background()
+	key := client.ObjectKeyFromObject(token)
+
+	owner := &githubv1.Token{}
+	if err := c.Get(ctx, key, owner); err != nil {
+		t.Fatal(err)
+	}
+	ownedSecret(t, c, owner, "ghs_live")
+	if err := c.Delete(ctx, owner); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, key, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	revoker := &recordingRevoker{}
+	s := NewTokenSecret(key, owner, "test", WithClient(c), WithRevoker(revoker.revoke))
+	if err := s.Finalize(ctx); err != nil {
+		t.Fatalf("Finalize() err = %v", err)
+	}
+
+	if len(revoker.revoked) != 1 || revoker.revoked[0] != "ghs_live" {
+		t.Errorf("revoked = %v, want [ghs_live]", revoker.revoked)
+	}
+	if err := c.Get(ctx, key, &githubv1.Token{}); err == nil {
+		t.Error("Token still present after finalizer removal")
+	}
+}
+
+// sequenceGHAIT mints ghs_1, ghs_2, ... in turn.
+type sequenceGHAIT struct {
+	fakeGHAIT
+}
+
+func (f *sequenceGHAIT) NewInstallationToken(ctx context.Context, id int64, options *github.InstallationTokenOptions) (*github.InstallationToken, error) {
+	token, err := f.fakeGHAIT.NewInstallationToken(ctx, id, options)
+	token.Token = github.Ptr(fmt.Sprintf("ghs_%d", f.mints))
+	return token, err
+}
+func (f *sequenceGHAIT) NewToken(ctx context.Context) (*github.InstallationToken, error) {
+	return f.NewInstallationToken(ctx, 0, nil)
+}
+func (f *sequenceGHAIT) NewTokenWithOptions(ctx context.Context, opts *github.InstallationTokenOptions) (*github.InstallationToken, error) {
+	return f.NewInstallationToken(ctx, 0, opts)
+}
+
+func TestReconcileFanOut_RevokesOnceEveryCopyIsWritten(t *testing.T) {
+	token := &githubv1.ClusterToken{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared", UID: "uid-shared"},
+		Spec: githubv1.ClusterTokenSpec{
+			Revoke:          true,
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+			Secret: githubv1.ClusterTokenSecretSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+			},
+		},
+	}
+	failTeamB := false
+	c := fake.NewClientBuilder().
+		WithScheme(testScheme(t)).
+		WithObjects(token, namespace("team-a", map[string]string{"tenant": "true"}), namespace("team-b", map[string]string{"tenant": "true"})).
+		WithStatusSubresource(token).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				if _, ok := obj.(*corev1.Secret); ok && failTeamB && obj.GetNamespace() == "team-b" {
+					return errors.New("apiserver unavailable")
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+		}).
+		Build()
+	ctx := context.Background()
+	key := types.NamespacedName{Name: token.Name}
+	gh := &sequenceGHAIT{}
+	revoker := &recordingRevoker{}
+	reconcile := func() {
+		t.Helper()
+		owner := &githubv1.ClusterToken{}
+		if err := c.Get(ctx, key, owner); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewTokenSecret(key, owner, "test", WithClient(c), WithGHApp(gh), WithRevoker(revoker.revoke)).Reconcile(ctx); err != nil {
+			t.Fatalf("Reconcile() err = %v", err)
+		}
+	}
+	reconcile()
+
+	// Force a rotation in which team-b's copy cannot be written: ghs_1,
+	// still held there, must stay live.
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "shared"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	delete(secret.Annotations, AnnotationChecksum)
+	if err := c.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	failTeamB = true
+	reconcile()
+	if gh.mints != 2 || len(revoker.revoked) != 0 {
+		t.Fatalf("mints = %d, revoked = %v after a partial write; want 2 mints and none revoked", gh.mints, revoker.revoked)
+	}
+
+	// The copies are out of step, so the next reconcile rewrites them all and
+	// revokes both outgoing tokens.
+	failTeamB = false
+	reconcile()
+	if gh.mints != 3 || !slices.Equal(revoker.revoked, []string{"ghs_2", "ghs_1"}) {
+		t.Errorf("mints = %d, revoked = %v; want 3 mints and [ghs_2 ghs_1] revoked", gh.mints, revoker.revoked)
+	}
+}
@@ -0,0 +1,102 @@
+package tokenmanager
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// SecretTemplateData is the input exposed to spec.secret.template entries.
+type SecretTemplateData struct {
+	Token          string
+	ExpiresAt      time.Time
+	AppID          int64
+	InstallationID int64
+	Repositories   []string
+}
+
+// TemplateError reports a spec.secret.template entry that failed to parse or
+// render. It is never transient: only a spec change can resolve it.
+type TemplateError struct {
+	Key string
+	Err error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("secret template %q: %v", e.Key, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+var templateFuncs = template.FuncMap{
+	"b64enc": func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	},
+	"join": func(sep string, elems []string) string {
+		return strings.Join(elems, sep)
+	},
+}
+
+// ParseSecretTemplate parses every entry of a spec.secret.template map,
+// returning a [*TemplateError] for the first (in key order) that is invalid.
+func ParseSecretTemplate(tmpl map[string]string) (map[string]*template.Template, error) {
+	parsed := make(map[string]*template.Template, len(tmpl))
+	for _, key := range slices.Sorted(maps.Keys(tmpl)) {
+		t, err := template.New(key).Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl[key])
+		if err != nil {
+			return nil, &TemplateError{Key: key, Err: err}
+		}
+		parsed[key] = t
+	}
+	return parsed, nil
+}
+
+// ValidateSecretTemplate parses every entry of a spec.secret.template map
+// and renders it against placeholder data, so that references to unknown
+// fields fail as they would when a token is minted.
+func ValidateSecretTemplate(tmpl map[string]string) error {
+	parsed, err := ParseSecretTemplate(tmpl)
+	if err != nil {
+		return err
+	}
+	_, err = renderSecretTemplate(parsed, SecretTemplateData{
+		Token:          "ghs_placeholder",
+		ExpiresAt:      time.Now().Add(time.Hour),
+		AppID:          1,
+		InstallationID: 1,
+		Repositories:   []string{"placeholder"},
+	})
+	return err
+}
+
+// renderSecretTemplate executes each parsed template against data.
+func renderSecretTemplate(parsed map[string]*template.Template, data SecretTemplateData) (map[string][]byte, error) {
+	out := make(map[string][]byte, len(parsed))
+	for _, key := range slices.Sorted(maps.Keys(parsed)) {
+		var buf bytes.Buffer
+		if err := parsed[key].Execute(&buf, data); err != nil {
+			return nil, &TemplateError{Key: key, Err: err}
+		}
+		out[key] = buf.Bytes()
+	}
+	return out, nil
+}
+
+// repositoryNames returns the names of the repositories an installation
+// token was scoped to; empty when it covers every repository.
+func repositoryNames(token *github.InstallationToken) []string {
+	names := make([]string, 0, len(token.Repositories))
+	for _, repo := range token.Repositories {
+		names = append(names, repo.GetName())
+	}
+	return names
+}
@@ -0,0 +1,79 @@
+package tokenmanager
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSecretTemplate_InvalidSyntax(t *testing.T) {
+	_, err := ParseSecretTemplate(map[string]string{
+		"ok":  "{{ .Token }}",
+		"bad": "{{ .Token ",
+	})
+	var templateErr *TemplateError
+	if !errors.As(err, &templateErr) {
+		t.Fatalf("ParseSecretTemplate() err = %v, want *TemplateError", err)
+	}
+	if templateErr.Key != "bad" {
+		t.Errorf("TemplateError.Key = %q, want bad", templateErr.Key)
+	}
+}
+
+func TestRenderSecretTemplate(t *testing.T) {
+	parsed, err := ParseSecretTemplate(map[string]string{
+		".npmrc":  "//npm.pkg.github.com/:_authToken={{ .Token }}",
+		"meta":    "{{ .AppID }}/{{ .InstallationID }}/{{ .ExpiresAt.Unix }}",
+		"repos":   `{{ .Repositories | join "," }}`,
+		"encoded": "{{ .Token | b64enc }}",
+	})
+	if err != nil {
+		t.Fatalf("ParseSecretTemplate() err = %v", err)
+	}
+
+	got, err := renderSecretTemplate(parsed, SecretTemplateData{
+		Token:          "ghs_abc",
+		ExpiresAt:      time.Unix(1700000000, 0),
+		AppID:          12,
+		InstallationID: 34,
+		Repositories:   []string{"foo", "bar"},
+	})
+	if err != nil {
+		t.Fatalf("renderSecretTemplate() err = %v", err)
+	}
+
+	want := map[string]string{
+		".npmrc":  "//npm.pkg.github.com/:_authToken=ghs_abc",
+		"meta":    "12/34/1700000000",
+		"repos":   "foo,bar",
+		"encoded": "Z2hzX2FiYw==",
+	}
+	for key, value := range want {
+		if string(got[key]) != value {
+			t.Errorf("data[%q] = %q, want %q", key, got[key], value)
+		}
+	}
+}
+
+func TestRenderSecretTemplate_UnknownField(t *testing.T) {
+	parsed, err := ParseSecretTemplate(map[string]string{"x": "{{ .Nope }}"})
+	if err != nil {
+		t.Fatalf("ParseSecretTemplate() err = %v", err)
+	}
+	_, err = renderSecretTemplate(parsed, SecretTemplateData{})
+	var templateErr *TemplateError
+	if !errors.As(err, &templateErr) {
+		t.Fatalf("renderSecretTemplate() err = %v, want *TemplateError", err)
+	}
+}
+
+func TestValidateSecretTemplate(t *testing.T) {
+	valid := map[string]string{"auth": `{{ printf "x:%s" .Token | b64enc }}`, "at": `{{ .ExpiresAt.Unix }}`}
+	if err := ValidateSecretTemplate(valid); err != nil {
+		t.Errorf("ValidateSecretTemplate() err = %v, want nil", err)
+	}
+	var templateErr *TemplateError
+	if err := ValidateSecretTemplate(map[string]string{"x": "{{ .Nope }}"}); !errors.As(err, &templateErr) || templateErr.Key != "x" {
+		t.Errorf("ValidateSecretTemplate() unknown field err = %v, want *TemplateError for x", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/go-github/v84/github"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -17,18 +18,43 @@ type TokenManager interface {
 	GetType() string
 	GetAppRef() *githubv1.AppReference
 	GetSecretBasicAuth() bool
+	GetSecretTemplate() map[string]string
+	GetSecretDockerConfigJSON() *githubv1.DockerConfigJSONSpec
+	GetSecretArgoCD() *githubv1.ArgoCDSpec
+	GetSecretGitFormats() []githubv1.GitFormat
+	GetSecretGitHost() string
+	GetSecretType() corev1.SecretType
 	GetInstallationID() int64
+	GetInstallationSelector() *githubv1.InstallationSelector
 	GetRefreshInterval() time.Duration
+	GetRefreshWindow() time.Duration
+	GetRefreshJitter() time.Duration
 	GetRetryInterval() time.Duration
+	GetRevoke() bool
+	GetShareToken() bool
 	GetSecretNamespace() string
 	GetSecretName() string
 	GetSecretLabels() map[string]string
 	GetSecretAnnotations() map[string]string
 	GetInstallationTokenOptions() *github.InstallationTokenOptions
+	GetRepositorySelector() *githubv1.RepositorySelector
 	GetManagedSecret() githubv1.ManagedSecret
 	UpdateManagedSecret() (changed bool)
 	GetStatusTimestamps() (createdAt, expiresAt time.Time)
 	SetStatusTimestamps(expiresAt time.Time)
+	SetStatusGrant(token *github.InstallationToken)
+	SetStatusSelectedRepositories(names []string) (changed bool)
 	GetStatusConditions() []metav1.Condition
 	SetStatusCondition(condition metav1.Condition) (changed bool)
+	GetStatusFailures() *githubv1.FailureStatus
+}
+
+// FanOutTokenManager is implemented by owners that can write a copy of their
+// Secret into every namespace matching a label selector.
+type FanOutTokenManager interface {
+	TokenManager
+
+	GetSecretNamespaceSelector() *metav1.LabelSelector
+	GetStatusTargetNamespaces() []string
+	SetStatusTargetNamespaces(namespaces []string) (changed bool)
 }
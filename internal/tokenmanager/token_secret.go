@@ -4,12 +4,17 @@ import (
 	"context"
 	"errors"
 	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/google/go-github/v84/github"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
@@ -19,12 +24,18 @@ import (
 
 	"github.com/isometry/ghait/v84"
 	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/backoff"
+	"github.com/isometry/github-token-manager/internal/events"
+	"github.com/isometry/github-token-manager/internal/ghapp"
 	"github.com/isometry/github-token-manager/internal/metrics"
 )
 
 const (
-	SecretTypeToken     = corev1.SecretType("github.as-code.io/token")
-	SecretTypeBasicAuth = corev1.SecretType("github.as-code.io/basic-auth")
+	SecretTypeToken     = githubv1.SecretTypeToken
+	SecretTypeBasicAuth = githubv1.SecretTypeBasicAuth
+	SecretTypeTemplate  = githubv1.SecretTypeTemplate
+	SecretTypeDocker    = githubv1.SecretTypeDocker
+	SecretTypeArgoCD    = githubv1.SecretTypeArgoCD
 	BasicAuthUsername   = "x-access-token"
 )
 
@@ -36,6 +47,14 @@ type tokenSecret struct {
 	controllerName string
 	ghait          ghait.GHAIT
 	metrics        *metrics.Recorder
+	events         *events.Recorder
+	revoke         RevokeFunc
+	github         *github.Client
+	installation   int64
+	repositories   []string
+	sharedTokens   *ghapp.SharedTokens
+	rateLimit      *ghapp.RateLimit
+	retryPolicy    backoff.Policy
 	*corev1.Secret
 }
 
@@ -65,6 +84,13 @@ func WithMetrics(m *metrics.Recorder) Option {
 	}
 }
 
+// WithEvents sets the recorder of the Kubernetes events emitted on the owner.
+func WithEvents(e *events.Recorder) Option {
+	return func(s *tokenSecret) {
+		s.events = e
+	}
+}
+
 func NewTokenSecret(key types.NamespacedName, owner TokenManager, controllerName string, options ...Option) *tokenSecret {
 	s := &tokenSecret{
 		key:            key,
@@ -74,17 +100,89 @@ func NewTokenSecret(key types.NamespacedName, owner TokenManager, controllerName
 	for _, option := range options {
 		option(s)
 	}
+	if s.github == nil {
+		s.github = github.NewClient(nil)
+	}
+	if s.revoke == nil {
+		s.revoke = newRevoker(s.github)
+	}
 	return s
 }
 
+// WithInstallationID sets the installation ID that the owner's
+// installation selector resolved to. An explicit spec.installationID still
+// takes precedence.
+func WithInstallationID(id int64) Option {
+	return func(s *tokenSecret) {
+		s.installation = id
+	}
+}
+
+// WithRepositories sets the names of the repositories the owner's
+// repository selector resolved to, which the token is limited to in place
+// of spec.repositories.
+func WithRepositories(names []string) Option {
+	return func(s *tokenSecret) {
+		s.repositories = names
+	}
+}
+
+// WithSharedTokens sets the installation tokens shared between owners of the
+// App, used when the owner sets spec.shareToken.
+func WithSharedTokens(t *ghapp.SharedTokens) Option {
+	return func(s *tokenSecret) {
+		s.sharedTokens = t
+	}
+}
+
+// WithRateLimit sets the rate limit budget of the App, which mints are
+// deferred by while it runs low.
+func WithRateLimit(l *ghapp.RateLimit) Option {
+	return func(s *tokenSecret) {
+		s.rateLimit = l
+	}
+}
+
 func (s *tokenSecret) NewInstallationToken(ctx context.Context) (*github.InstallationToken, error) {
 	installationId := s.owner.GetInstallationID()
+	if installationId == 0 {
+		installationId = s.installation
+	}
 	options := s.owner.GetInstallationTokenOptions()
+	if s.repositories != nil {
+		options.Repositories = s.repositories
+	}
 
-	start := time.Now()
-	token, err := s.ghait.NewInstallationToken(ctx, installationId, options)
-	s.metrics.RecordGitHubAPICall(ctx, s.controllerName, time.Since(start), err)
-	return token, err
+	mint := func(ctx context.Context) (*github.InstallationToken, error) {
+		if err := s.rateLimit.Reserve(); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		token, err := s.ghait.NewInstallationToken(ctx, installationId, options)
+		s.metrics.RecordGitHubAPICall(ctx, s.controllerName, time.Since(start), err)
+		if state, ok := s.rateLimit.State(); ok {
+			s.metrics.RecordRateLimit(ctx, s.rateLimit.Key().String(), state.Remaining, state.Reset)
+		}
+		return token, err
+	}
+	if s.sharedTokens == nil || !s.owner.GetShareToken() {
+		return mint(ctx)
+	}
+
+	// A shared token is reused until this owner would refresh it anyway.
+	fresh := func(expiresAt time.Time) bool {
+		return time.Now().Before(s.refreshAt(expiresAt))
+	}
+	token, hit, err := s.sharedTokens.Token(ctx, s.installationID(), options, fresh, mint)
+	if err != nil {
+		return nil, err
+	}
+	result := metrics.CacheMiss
+	if hit {
+		result = metrics.CacheHit
+	}
+	s.metrics.RecordTokenCacheLookup(ctx, s.controllerName, result)
+	return token, nil
 }
 
 func (s *tokenSecret) RefreshOwner(ctx context.Context) error {
@@ -101,13 +199,26 @@ func (s *tokenSecret) recordExpiry(ctx context.Context) {
 func (s *tokenSecret) Reconcile(ctx context.Context) (result reconcile.Result, err error) {
 	log := s.log.WithValues("func", "Reconcile")
 
+	if err := s.EnsureFinalizer(ctx); err != nil {
+		return s.apiFailed(ctx, err, "failed to update finalizers", metrics.ReasonStatusUpdate)
+	}
+
 	managedSecret := s.owner.GetManagedSecret()
 
 	if !managedSecret.IsUnset() && !managedSecret.MatchesSpec(s.owner) {
-		if err := s.DeleteSecret(ctx, managedSecret.Key()); err != nil {
-			log.Error(err, "failed to delete managed secret")
-			return result, err
+		if managedSecret.Namespace == "" {
+			// An empty namespace records copies fanned out by namespaceSelector.
+			err = s.deleteFanOutSecrets(ctx)
+		} else {
+			err = s.DeleteSecret(ctx, managedSecret.Key())
 		}
+		if err != nil {
+			return s.apiFailed(ctx, err, "failed to delete managed secret", metrics.ReasonSecretUpdate)
+		}
+	}
+
+	if owner, ok := s.fanOutOwner(); ok {
+		return s.reconcileFanOut(ctx, owner)
 	}
 
 	secretKey := types.NamespacedName{
@@ -119,8 +230,7 @@ func (s *tokenSecret) Reconcile(ctx context.Context) (result reconcile.Result, e
 
 	err = s.client.Get(ctx, secretKey, secret)
 	if err != nil && !apierrors.IsNotFound(err) {
-		log.Error(err, "failed to get secret")
-		return result, err
+		return s.apiFailed(ctx, err, "failed to get secret", metrics.ReasonSecretUpdate)
 	}
 
 	if apierrors.IsNotFound(err) {
@@ -128,15 +238,22 @@ func (s *tokenSecret) Reconcile(ctx context.Context) (result reconcile.Result, e
 		if err := s.CreateSecret(ctx); err != nil {
 			s.metrics.RecordTokenRefresh(ctx, s.controllerName, metrics.ResultError)
 			s.metrics.RecordTokenRefreshDuration(ctx, s.controllerName, metrics.OperationCreate, time.Since(start))
+			if failure := classifyGitHubError(err, s.installationID()); failure != nil {
+				return s.githubFailed(ctx, failure)
+			}
 			if errors.Is(err, ghait.TransientError{}) {
 				s.metrics.RecordReconcileError(ctx, s.controllerName, metrics.ReasonTransient)
+				s.events.Warning(s.owner, events.ReasonTransientFailure, events.ActionMintToken, err.Error())
 				log.Error(err, "transient error creating secret")
-				return reconcile.Result{RequeueAfter: s.owner.GetRetryInterval()}, nil
+				return s.failed(ctx, nil, 0)
+			}
+			if templateErr := (*TemplateError)(nil); errors.As(err, &templateErr) {
+				return result, s.templateFailed(ctx, templateErr)
 			}
 
 			s.metrics.RecordReconcileError(ctx, s.controllerName, metrics.ReasonSecretCreate)
 			log.Error(err, "fatal error creating secret")
-			return result, err
+			return s.failed(ctx, nil, 0)
 		}
 
 		s.metrics.RecordTokenRefresh(ctx, s.controllerName, metrics.ResultSuccess)
@@ -145,7 +262,7 @@ func (s *tokenSecret) Reconcile(ctx context.Context) (result reconcile.Result, e
 		s.metrics.EnsureTokenActive(ctx, s.controllerName, s.key.String())
 		s.recordExpiry(ctx)
 
-		return reconcile.Result{RequeueAfter: s.owner.GetRefreshInterval()}, nil
+		return reconcile.Result{RequeueAfter: s.nextRefresh()}, nil
 	}
 
 	if !metav1.IsControlledBy(secret, s.owner) {
@@ -155,31 +272,58 @@ func (s *tokenSecret) Reconcile(ctx context.Context) (result reconcile.Result, e
 			Reason:  "Failed",
 			Message: "Secret already exists",
 		}
-		if err := s.UpdateTokenStatus(ctx, &condition, nil, false); err != nil {
-			log.Error(err, "failed to update token status")
-			return result, err
-		}
 		s.metrics.RecordReconcileError(ctx, s.controllerName, metrics.ReasonOwnership)
-		err := errors.New("existing secret not owned by token")
-		log.Error(err, "ownership mismatch", "token", s.owner)
-		return result, err
+		s.events.Warning(s.owner, events.ReasonOwnershipConflict, events.ActionUpdateSecret,
+			"Secret "+secretKey.String()+" already exists and is not owned by this "+s.owner.GetType())
+		log.Error(errors.New("existing secret not owned by token"), "ownership mismatch", "token", s.owner)
+		return s.failed(ctx, &condition, 0)
 	}
 
 	s.Secret = secret
 
+	if dueIn := s.freshFor(secret); dueIn > 0 {
+		// The token is still fresh: keep it, only bringing the Secret's
+		// labels and annotations in line with the spec.
+		if err := s.restoreMetadata(ctx, secret); err != nil {
+			return s.apiFailed(ctx, err, "failed to update secret metadata", metrics.ReasonSecretUpdate)
+		}
+		if !meta.IsStatusConditionTrue(s.owner.GetStatusConditions(), githubv1.ConditionTypeReady) ||
+			s.owner.GetStatusFailures().ConsecutiveFailures > 0 {
+			condition := metav1.Condition{
+				Type:    githubv1.ConditionTypeReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  "Updated",
+				Message: "Updated Secret",
+			}
+			if err := s.UpdateTokenStatus(ctx, &condition, nil, true); err != nil {
+				return result, err
+			}
+		}
+		s.metrics.RecordMintSkipped(ctx, s.controllerName)
+		s.metrics.EnsureTokenActive(ctx, s.controllerName, s.key.String())
+		return reconcile.Result{RequeueAfter: dueIn}, nil
+	}
+
 	start := time.Now()
 	if err := s.UpdateSecret(ctx); err != nil {
 		s.metrics.RecordTokenRefresh(ctx, s.controllerName, metrics.ResultError)
 		s.metrics.RecordTokenRefreshDuration(ctx, s.controllerName, metrics.OperationUpdate, time.Since(start))
+		if failure := classifyGitHubError(err, s.installationID()); failure != nil {
+			return s.githubFailed(ctx, failure)
+		}
 		if errors.Is(err, ghait.TransientError{}) {
 			s.metrics.RecordReconcileError(ctx, s.controllerName, metrics.ReasonTransient)
+			s.events.Warning(s.owner, events.ReasonTransientFailure, events.ActionMintToken, err.Error())
 			log.Error(err, "transient error updating secret")
-			return reconcile.Result{RequeueAfter: s.owner.GetRetryInterval()}, nil
+			return s.failed(ctx, nil, 0)
+		}
+		if templateErr := (*TemplateError)(nil); errors.As(err, &templateErr) {
+			return result, s.templateFailed(ctx, templateErr)
 		}
 
 		s.metrics.RecordReconcileError(ctx, s.controllerName, metrics.ReasonSecretUpdate)
 		log.Error(err, "fatal error updating secret")
-		return result, err
+		return s.failed(ctx, nil, 0)
 	}
 
 	s.metrics.RecordTokenRefresh(ctx, s.controllerName, metrics.ResultSuccess)
@@ -188,13 +332,19 @@ func (s *tokenSecret) Reconcile(ctx context.Context) (result reconcile.Result, e
 	s.metrics.EnsureTokenActive(ctx, s.controllerName, s.key.String())
 	s.recordExpiry(ctx)
 
-	return reconcile.Result{RequeueAfter: s.owner.GetRefreshInterval()}, nil
+	return reconcile.Result{RequeueAfter: s.nextRefresh()}, nil
 }
 
 func (s *tokenSecret) CreateSecret(ctx context.Context) error {
 	log := s.log.WithValues("func", "CreateSecret")
 	log.Info("creating secret")
 
+	parsed, err := ParseSecretTemplate(s.owner.GetSecretTemplate())
+	if err != nil {
+		s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationCreate, metrics.ResultError)
+		return err
+	}
+
 	installationToken, err := s.NewInstallationToken(ctx)
 	if err != nil {
 		log.Error(err, "failed to get installation token")
@@ -202,21 +352,22 @@ func (s *tokenSecret) CreateSecret(ctx context.Context) error {
 		return err
 	}
 
-	secretType := SecretTypeToken
-	if s.owner.GetSecretBasicAuth() {
-		secretType = SecretTypeBasicAuth
+	data, err := s.SecretData(installationToken, parsed)
+	if err != nil {
+		s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationCreate, metrics.ResultError)
+		return err
 	}
 
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace:   s.owner.GetSecretNamespace(),
 			Name:        s.owner.GetSecretName(),
-			Labels:      s.SecretLabels(),
-			Annotations: s.owner.GetSecretAnnotations(),
+			Annotations: s.SecretAnnotations(data, installationToken.GetExpiresAt().Time),
 		},
-		Data: s.SecretData(installationToken.GetToken()),
-		Type: secretType,
+		Data: data,
+		Type: s.owner.GetSecretType(),
 	}
+	s.applyMetadata(secret)
 
 	s.Secret = secret
 
@@ -231,6 +382,8 @@ func (s *tokenSecret) CreateSecret(ctx context.Context) error {
 		s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationCreate, metrics.ResultError)
 		return err
 	}
+	s.events.Normal(s.owner, events.ReasonSecretCreated, events.ActionCreateSecret,
+		"Created Secret "+client.ObjectKeyFromObject(s.Secret).String())
 
 	condition := metav1.Condition{
 		Type:    githubv1.ConditionTypeReady,
@@ -238,8 +391,7 @@ func (s *tokenSecret) CreateSecret(ctx context.Context) error {
 		Reason:  "Created",
 		Message: "Created Secret",
 	}
-	expiresAt := installationToken.ExpiresAt.Time
-	if err := s.UpdateTokenStatus(ctx, &condition, &expiresAt, true); err != nil {
+	if err := s.UpdateTokenStatus(ctx, &condition, installationToken, true); err != nil {
 		log.Error(err, "failed to update token status")
 		return err
 	}
@@ -251,6 +403,12 @@ func (s *tokenSecret) UpdateSecret(ctx context.Context) error {
 	log := s.log.WithValues("func", "UpdateSecret")
 	log.Info("updating secret")
 
+	parsed, err := ParseSecretTemplate(s.owner.GetSecretTemplate())
+	if err != nil {
+		s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationUpdate, metrics.ResultError)
+		return err
+	}
+
 	installationToken, err := s.NewInstallationToken(ctx)
 	if err != nil {
 		log.Error(err, "failed to get installation token")
@@ -258,13 +416,28 @@ func (s *tokenSecret) UpdateSecret(ctx context.Context) error {
 		return err
 	}
 
-	s.Data = s.SecretData(installationToken.GetToken())
+	data, err := s.SecretData(installationToken, parsed)
+	if err != nil {
+		s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationUpdate, metrics.ResultError)
+		return err
+	}
+
+	previousToken := outgoingToken(s.Secret)
+
+	s.Data = data
+	s.applyMetadata(s.Secret)
+	if s.Annotations == nil {
+		s.Annotations = make(map[string]string)
+	}
+	maps.Copy(s.Annotations, s.SecretAnnotations(data, installationToken.GetExpiresAt().Time))
 
 	if err := s.client.Update(ctx, s.Secret); err != nil {
 		log.Error(err, "failed to update secret")
 		s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationUpdate, metrics.ResultError)
 		return err
 	}
+	s.events.Normal(s.owner, events.ReasonSecretRotated, events.ActionUpdateSecret,
+		"Rotated the token in Secret "+client.ObjectKeyFromObject(s.Secret).String())
 
 	condition := metav1.Condition{
 		Type:    githubv1.ConditionTypeReady,
@@ -272,12 +445,15 @@ func (s *tokenSecret) UpdateSecret(ctx context.Context) error {
 		Reason:  "Updated",
 		Message: "Updated Secret",
 	}
-	expiresAt := installationToken.ExpiresAt.Time
-	if err := s.UpdateTokenStatus(ctx, &condition, &expiresAt, true); err != nil {
+	if err := s.UpdateTokenStatus(ctx, &condition, installationToken, true); err != nil {
 		log.Error(err, "failed to update token status")
 		return err
 	}
 
+	if s.owner.GetRevoke() && previousToken != installationToken.GetToken() {
+		s.revokeToken(ctx, previousToken)
+	}
+
 	return nil
 }
 
@@ -308,6 +484,8 @@ func (s *tokenSecret) DeleteSecret(ctx context.Context, key types.NamespacedName
 
 	s.metrics.RecordSecretOperation(ctx, s.controllerName, metrics.OperationDelete, metrics.ResultSuccess)
 	s.metrics.RemoveTokenActive(ctx, s.controllerName, s.key.String())
+	s.events.Normal(s.owner, events.ReasonSecretDeleted, events.ActionDeleteSecret,
+		"Deleted old Secret "+key.String())
 
 	condition := metav1.Condition{
 		Type:    githubv1.ConditionTypeReady,
@@ -323,11 +501,30 @@ func (s *tokenSecret) DeleteSecret(ctx context.Context, key types.NamespacedName
 	return nil
 }
 
+// templateFailed surfaces a spec.secret.template error as a Ready=False
+// condition and counts the failure. No requeue is requested: only a spec
+// change can fix it, and that triggers a fresh reconcile.
+func (s *tokenSecret) templateFailed(ctx context.Context, templateErr *TemplateError) error {
+	log := s.log.WithValues("func", "templateFailed")
+
+	s.metrics.RecordReconcileError(ctx, s.controllerName, metrics.ReasonTemplate)
+	log.Error(templateErr, "invalid secret template")
+
+	condition := metav1.Condition{
+		Type:    githubv1.ConditionTypeReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  githubv1.ReasonTemplateError,
+		Message: templateErr.Error(),
+	}
+	return s.recordFailure(ctx, &condition)
+}
+
 // UpdateTokenStatus refreshes the owner, applies the given mutations, and
 // writes status if anything changed, retrying on conflict. Pass nil for
-// condition or expiresAt to leave them untouched; updateManaged toggles the
-// ManagedSecret refresh.
-func (s *tokenSecret) UpdateTokenStatus(ctx context.Context, condition *metav1.Condition, expiresAt *time.Time, updateManaged bool) error {
+// condition or installationToken to leave them untouched; updateManaged
+// toggles the ManagedSecret refresh. A Ready=True condition resets the count
+// of failed reconciles.
+func (s *tokenSecret) UpdateTokenStatus(ctx context.Context, condition *metav1.Condition, installationToken *github.InstallationToken, updateManaged bool) error {
 	log := s.log.WithValues("func", "UpdateTokenStatus")
 
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -339,8 +536,13 @@ func (s *tokenSecret) UpdateTokenStatus(ctx context.Context, condition *metav1.C
 		if condition != nil && s.owner.SetStatusCondition(*condition) {
 			changed = true
 		}
-		if expiresAt != nil {
-			s.owner.SetStatusTimestamps(*expiresAt)
+		if condition != nil && condition.Status == metav1.ConditionTrue && s.owner.GetStatusFailures().ResetFailures() {
+			changed = true
+		}
+		if installationToken != nil {
+			s.owner.SetStatusTimestamps(installationToken.GetExpiresAt().Time)
+			s.owner.SetStatusGrant(installationToken)
+			s.owner.SetStatusSelectedRepositories(s.repositories)
 			changed = true
 		}
 		if updateManaged && s.owner.UpdateManagedSecret() {
@@ -359,25 +561,150 @@ func (s *tokenSecret) UpdateTokenStatus(ctx context.Context, condition *metav1.C
 	return nil
 }
 
+// AnnotationManagedLabels records the keys of the labels the operator
+// applied to a managed Secret, so that those no longer wanted can be removed.
+const AnnotationManagedLabels = "github.as-code.io/managed-labels"
+
+// applyLabels sets the managed labels on secret, removing any the operator
+// applied earlier that are no longer wanted, such as the Argo CD secret-type
+// label after the owner leaves Argo CD mode. The keys applied are recorded in
+// the AnnotationManagedLabels annotation. It reports whether secret changed.
+func (s *tokenSecret) applyLabels(secret *corev1.Secret) bool {
+	want := s.SecretLabels()
+	keys := strings.Join(slices.Sorted(maps.Keys(want)), ",")
+	before := maps.Clone(secret.Labels)
+	if secret.Labels == nil {
+		secret.Labels = make(map[string]string, len(want))
+	}
+	if previous := secret.Annotations[AnnotationManagedLabels]; previous != "" {
+		for key := range strings.SplitSeq(previous, ",") {
+			if _, ok := want[key]; !ok {
+				delete(secret.Labels, key)
+			}
+		}
+	}
+	maps.Copy(secret.Labels, want)
+	changed := !maps.Equal(before, secret.Labels) || secret.Annotations[AnnotationManagedLabels] != keys
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string, 1)
+	}
+	secret.Annotations[AnnotationManagedLabels] = keys
+	return changed
+}
+
+// AnnotationManagedAnnotations records the keys of the spec.secret
+// annotations the operator applied to a managed Secret, so that those removed
+// from the spec can be removed from the Secret.
+const AnnotationManagedAnnotations = "github.as-code.io/managed-annotations"
+
+// applyAnnotations sets the owner's spec.secret annotations on secret,
+// removing any it applied earlier that are no longer wanted, and recording the
+// keys applied in the AnnotationManagedAnnotations annotation. Annotations the
+// operator maintains itself are left alone. It reports whether secret changed.
+func (s *tokenSecret) applyAnnotations(secret *corev1.Secret) bool {
+	want := maps.Clone(s.owner.GetSecretAnnotations())
+	for _, key := range []string{AnnotationExpiresAt, AnnotationChecksum, AnnotationManagedLabels, AnnotationManagedAnnotations} {
+		delete(want, key)
+	}
+	keys := strings.Join(slices.Sorted(maps.Keys(want)), ",")
+	before := maps.Clone(secret.Annotations)
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string, len(want)+1)
+	}
+	if previous := secret.Annotations[AnnotationManagedAnnotations]; previous != "" {
+		for key := range strings.SplitSeq(previous, ",") {
+			if _, ok := want[key]; !ok {
+				delete(secret.Annotations, key)
+			}
+		}
+	}
+	maps.Copy(secret.Annotations, want)
+	if keys == "" {
+		delete(secret.Annotations, AnnotationManagedAnnotations)
+	} else {
+		secret.Annotations[AnnotationManagedAnnotations] = keys
+	}
+	return !maps.Equal(before, secret.Annotations)
+}
+
+// applyMetadata sets the managed labels and annotations on secret, reporting
+// whether it changed.
+func (s *tokenSecret) applyMetadata(secret *corev1.Secret) bool {
+	labels := s.applyLabels(secret)
+	annotations := s.applyAnnotations(secret)
+	return labels || annotations
+}
+
+// SecretLabels returns the labels for the managed Secret: the owner's
+// spec.secret labels, overridden by the labels identifying the owner, which
+// fan-out relies on to find its copies.
 func (s *tokenSecret) SecretLabels() map[string]string {
-	secretLabels := map[string]string{
+	secretLabels := maps.Clone(s.owner.GetSecretLabels())
+	if secretLabels == nil {
+		secretLabels = make(map[string]string, 4)
+	}
+	maps.Copy(secretLabels, map[string]string{
 		"app.kubernetes.io/name":       s.owner.GetType(),
 		"app.kubernetes.io/instance":   s.owner.GetName(),
 		"app.kubernetes.io/part-of":    "github-token-manager",
 		"app.kubernetes.io/created-by": "github-token-manager",
+	})
+	if argoCD := s.owner.GetSecretArgoCD(); argoCD != nil {
+		secretLabels[githubv1.ArgoCDSecretTypeLabel] = argoCD.GetSecretType()
 	}
-	maps.Copy(secretLabels, s.owner.GetSecretLabels())
 	return secretLabels
 }
 
-func (s *tokenSecret) SecretData(installationToken string) map[string][]byte {
-	if s.owner.GetSecretBasicAuth() {
+// SecretData returns the data for the managed Secret: a docker config JSON or
+// the rendered spec.secret.template when requested, else 'username'/'password'
+// or 'token'; plus any requested git credential files.
+func (s *tokenSecret) SecretData(installationToken *github.InstallationToken, parsed map[string]*template.Template) (map[string][]byte, error) {
+	data, err := s.primarySecretData(installationToken, parsed)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(data, gitCredentialFiles(s.owner.GetSecretGitFormats(), s.owner.GetSecretGitHost(), installationToken.GetToken()))
+	return data, nil
+}
+
+func (s *tokenSecret) primarySecretData(installationToken *github.InstallationToken, parsed map[string]*template.Template) (map[string][]byte, error) {
+	if dockerConfig := s.owner.GetSecretDockerConfigJSON(); dockerConfig != nil {
+		config, err := dockerConfigJSON(dockerConfig.Registries(), installationToken.GetToken())
+		if err != nil {
+			return nil, err
+		}
 		return map[string][]byte{
+			corev1.DockerConfigJsonKey: config,
+		}, nil
+	}
+	if argoCD := s.owner.GetSecretArgoCD(); argoCD != nil {
+		data := map[string][]byte{
+			"type":     []byte("git"),
+			"url":      []byte(argoCD.GetURL()),
 			"username": []byte(BasicAuthUsername),
-			"password": []byte(installationToken),
+			"password": []byte(installationToken.GetToken()),
 		}
+		if argoCD.IncludeGitHubAppID {
+			data["githubAppID"] = []byte(strconv.FormatInt(s.ghait.GetAppID(), 10))
+		}
+		return data, nil
 	}
-	return map[string][]byte{
-		"token": []byte(installationToken),
+	if len(parsed) > 0 {
+		return renderSecretTemplate(parsed, SecretTemplateData{
+			Token:          installationToken.GetToken(),
+			ExpiresAt:      installationToken.GetExpiresAt().Time,
+			AppID:          s.ghait.GetAppID(),
+			InstallationID: s.installationID(),
+			Repositories:   repositoryNames(installationToken),
+		})
 	}
+	if s.owner.GetSecretBasicAuth() {
+		return map[string][]byte{
+			"username": []byte(BasicAuthUsername),
+			"password": []byte(installationToken.GetToken()),
+		}, nil
+	}
+	return map[string][]byte{
+		"token": []byte(installationToken.GetToken()),
+	}, nil
 }
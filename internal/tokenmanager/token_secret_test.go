@@ -0,0 +1,254 @@
+package tokenmanager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	toolsevents "k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/backoff"
+	"github.com/isometry/github-token-manager/internal/events"
+)
+
+func TestApplyLabels_LeavingArgoCDModeRemovesLabel(t *testing.T) {
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "repo"},
+		Spec: githubv1.TokenSpec{
+			Secret: githubv1.TokenSecretSpec{ArgoCD: &githubv1.ArgoCDSpec{}},
+		},
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "repo"}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Labels: map[string]string{"team": "platform"},
+	}}
+
+	if !NewTokenSecret(key, token, "test").applyLabels(secret) {
+		t.Fatal("applyLabels() = false, want true for a new Secret")
+	}
+	if secret.Labels[githubv1.ArgoCDSecretTypeLabel] != "repo-creds" {
+		t.Fatalf("labels = %v, want the Argo CD secret-type label", secret.Labels)
+	}
+
+	token.Spec.Secret.ArgoCD = nil
+	token.Spec.Secret.Template = map[string]string{"token": "{{ .Token }}"}
+	if !NewTokenSecret(key, token, "test").applyLabels(secret) {
+		t.Fatal("applyLabels() = false, want true after leaving Argo CD mode")
+	}
+	if _, ok := secret.Labels[githubv1.ArgoCDSecretTypeLabel]; ok {
+		t.Errorf("labels = %v, want the Argo CD secret-type label removed", secret.Labels)
+	}
+	if secret.Labels["team"] != "platform" {
+		t.Errorf("labels = %v, want labels the operator did not apply kept", secret.Labels)
+	}
+	if NewTokenSecret(key, token, "test").applyLabels(secret) {
+		t.Error("applyLabels() = true, want false once in sync")
+	}
+}
+
+func TestSecretLabels_OwnerLabelsTakePrecedence(t *testing.T) {
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "repo"},
+		Spec: githubv1.TokenSpec{
+			Secret: githubv1.TokenSecretSpec{Labels: map[string]string{
+				"team":                       "platform",
+				"app.kubernetes.io/name":     "other",
+				"app.kubernetes.io/instance": "other",
+			}},
+		},
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "repo"}
+
+	labels := NewTokenSecret(key, token, "test").SecretLabels()
+	if labels["app.kubernetes.io/name"] != "Token" || labels["app.kubernetes.io/instance"] != "repo" {
+		t.Errorf("labels = %v, want the owner's identifying labels", labels)
+	}
+	if labels["team"] != "platform" {
+		t.Errorf("labels = %v, want spec.secret labels kept", labels)
+	}
+}
+
+func TestReconcile_RecordsEvents(t *testing.T) {
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "evented", UID: "uid-evented"},
+		Spec: githubv1.TokenSpec{
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+		},
+	}
+	rival := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rival", UID: "uid-rival"},
+		Spec: githubv1.TokenSpec{
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+			Secret:          githubv1.TokenSecretSpec{Name: "evented"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(token, rival).WithStatusSubresource(token, rival).Build()
+	ctx := context.Background()
+	fakeRecorder := toolsevents.NewFakeRecorder(10)
+	recorder := events.NewRecorder(fakeRecorder)
+
+	reconcile := func(owner *githubv1.Token) error {
+		t.Helper()
+		key := client.ObjectKeyFromObject(owner)
+		current := &githubv1.Token{}
+		if err := c.Get(ctx, key, current); err != nil {
+			t.Fatal(err)
+		}
+		_, err := NewTokenSecret(key, current, "test", WithClient(c), WithGHApp(&fakeGHAIT{}), WithEvents(recorder)).Reconcile(ctx)
+		return err
+	}
+	rotate := func() {
+		t.Helper()
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(token), secret); err != nil {
+			t.Fatal(err)
+		}
+		secret.Data["token"] = []byte("tampered")
+		if err := c.Update(ctx, secret); err != nil {
+			t.Fatal(err)
+		}
+		if err := reconcile(token); err != nil {
+			t.Fatalf("Reconcile() err = %v", err)
+		}
+	}
+	expect := func(want ...string) {
+		t.Helper()
+		var got []string
+		for len(fakeRecorder.Events) > 0 {
+			got = append(got, <-fakeRecorder.Events)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("events = %q, want %q", got, want)
+		}
+	}
+
+	if err := reconcile(token); err != nil {
+		t.Fatalf("Reconcile() err = %v", err)
+	}
+	expect("Normal SecretCreated Created Secret default/evented")
+
+	rotate()
+	expect("Normal SecretRotated Rotated the token in Secret default/evented")
+
+	// Further rotations are suppressed, so hourly refreshes stay quiet.
+	rotate()
+	expect()
+
+	if err := reconcile(rival); err != nil {
+		t.Fatalf("Reconcile() of a Token whose Secret is owned by another err = %v", err)
+	}
+	expect("Warning OwnershipConflict Secret default/evented already exists and is not owned by this Token")
+}
+
+func TestReconcile_BacksOffOnFailure(t *testing.T) {
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "holder", UID: "uid-holder"},
+		Spec: githubv1.TokenSpec{
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+		},
+	}
+	rival := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rival", UID: "uid-rival"},
+		Spec: githubv1.TokenSpec{
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+			RetryInterval:   metav1.Duration{Duration: 3 * time.Second},
+			Secret:          githubv1.TokenSecretSpec{Name: "holder"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(token, rival).WithStatusSubresource(token, rival).Build()
+	ctx := context.Background()
+	policy := backoff.Policy{BaseDelay: time.Second}
+
+	reconcile := func(owner *githubv1.Token) (time.Duration, *githubv1.Token) {
+		t.Helper()
+		key := client.ObjectKeyFromObject(owner)
+		current := &githubv1.Token{}
+		if err := c.Get(ctx, key, current); err != nil {
+			t.Fatal(err)
+		}
+		result, err := NewTokenSecret(key, current, "test", WithClient(c), WithGHApp(&fakeGHAIT{}), WithBackoff(policy)).Reconcile(ctx)
+		if err != nil {
+			t.Fatalf("Reconcile() err = %v", err)
+		}
+		if err := c.Get(ctx, key, current); err != nil {
+			t.Fatal(err)
+		}
+		return result.RequeueAfter, current
+	}
+
+	reconcile(token)
+
+	// Each failure in a row doubles the delay, up to spec.retryInterval.
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		requeueAfter, current := reconcile(rival)
+		if requeueAfter != want {
+			t.Errorf("failure %d: RequeueAfter = %v, want %v", i+1, requeueAfter, want)
+		}
+		if got := current.Status.ConsecutiveFailures; got != int32(i+1) {
+			t.Errorf("failure %d: consecutiveFailures = %d, want %d", i+1, got, i+1)
+		}
+		if current.Status.LastFailureTime == nil {
+			t.Errorf("failure %d: lastFailureTime unset", i+1)
+		}
+	}
+
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "holder"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, current := reconcile(rival); current.Status.ConsecutiveFailures != 0 {
+		t.Errorf("consecutiveFailures = %d after success, want 0", current.Status.ConsecutiveFailures)
+	}
+}
+
+func TestReconcile_BacksOffOnAPIError(t *testing.T) {
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "holder", UID: "uid-holder"},
+		Spec: githubv1.TokenSpec{
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+		},
+	}
+	errAPI := errors.New("apiserver unavailable")
+	c := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(token).WithStatusSubresource(token).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if _, ok := obj.(*corev1.Secret); ok {
+					return errAPI
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).Build()
+	ctx := context.Background()
+	key := client.ObjectKeyFromObject(token)
+
+	result, err := NewTokenSecret(key, token, "test", WithClient(c), WithGHApp(&fakeGHAIT{}),
+		WithBackoff(backoff.Policy{BaseDelay: time.Second})).Reconcile(ctx)
+	if err != nil || result.RequeueAfter != time.Second {
+		t.Fatalf("Reconcile() = %v, %v; want a 1s backoff and no error", result, err)
+	}
+	current := &githubv1.Token{}
+	if err := c.Get(ctx, key, current); err != nil {
+		t.Fatal(err)
+	}
+	if current.Status.ConsecutiveFailures != 1 {
+		t.Errorf("consecutiveFailures = %d, want 1", current.Status.ConsecutiveFailures)
+	}
+	ready := meta.FindStatusCondition(current.Status.Conditions, githubv1.ConditionTypeReady)
+	if ready == nil || ready.Status != metav1.ConditionFalse || !strings.Contains(ready.Message, errAPI.Error()) {
+		t.Errorf("Ready = %+v, want False reporting %q", ready, errAPI)
+	}
+}
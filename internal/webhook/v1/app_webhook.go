@@ -0,0 +1,156 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/isometry/ghait/v84/provider"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+)
+
+var applog = logf.Log.WithName("app-resource")
+
+var (
+	// awsKeyPattern matches a KMS key alias, key ID (including multi-region
+	// keys) or key/alias ARN.
+	awsKeyPattern = regexp.MustCompile(`^(alias/[a-zA-Z0-9/_-]+|(mrk-)?[0-9a-f]{32}|[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}|arn:aws[a-z-]*:kms:[a-z0-9-]+:[0-9]{12}:(key|alias)/.+)$`)
+	// gcpKeyPattern matches a Cloud KMS crypto key or crypto key version
+	// resource name.
+	gcpKeyPattern = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+(/cryptoKeyVersions/[^/]+)?$`)
+)
+
+// SetupAppWebhookWithManager registers the validating webhook for App.
+func SetupAppWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &githubv1.App{}).
+		WithValidator(&AppCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-github-as-code-io-v1-app,mutating=false,failurePolicy=fail,sideEffects=None,groups=github.as-code.io,resources=apps,verbs=create;update,versions=v1,name=vapp-v1.kb.io,admissionReviewVersions=v1
+
+// AppCustomValidator validates Apps on create and update.
+type AppCustomValidator struct {
+	Client client.Reader
+}
+
+// ValidateCreate implements [admission.Validator].
+func (v *AppCustomValidator) ValidateCreate(ctx context.Context, app *githubv1.App) (admission.Warnings, error) {
+	applog.V(1).Info("validate create", "name", app.Name, "namespace", app.Namespace)
+	return v.validate(ctx, app)
+}
+
+// ValidateUpdate implements [admission.Validator].
+func (v *AppCustomValidator) ValidateUpdate(ctx context.Context, old, app *githubv1.App) (admission.Warnings, error) {
+	applog.V(1).Info("validate update", "name", app.Name, "namespace", app.Namespace)
+	if specUnchanged(old, app, old.Spec, app.Spec) {
+		return nil, nil
+	}
+	return v.validate(ctx, app)
+}
+
+// ValidateDelete implements [admission.Validator].
+func (v *AppCustomValidator) ValidateDelete(context.Context, *githubv1.App) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *AppCustomValidator) validate(ctx context.Context, app *githubv1.App) (admission.Warnings, error) {
+	errs := validateAppKey(app.Spec.Provider, app.Spec.Key)
+	if len(errs) > 0 {
+		return nil, apierrors.NewInvalid(githubv1.GroupVersion.WithKind("App").GroupKind(), app.Name, errs)
+	}
+
+	var warnings admission.Warnings
+	if ref := app.Spec.KeyRef; ref != nil {
+		key := types.NamespacedName{Namespace: app.Namespace, Name: ref.Name}
+		if err := v.Client.Get(ctx, key, &corev1.Secret{}); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			warnings = append(warnings, fmt.Sprintf("Secret %s does not exist; the App will not be Ready until it does", key))
+		}
+	}
+	return warnings, nil
+}
+
+// validateAppKey rejects a provider that is not compiled into this build of
+// the operator, and a key whose shape the provider cannot use.
+func validateAppKey(providerName, key string) field.ErrorList {
+	providerPath := specPath.Child("provider")
+	keyPath := specPath.Child("key")
+
+	providers := appProviders()
+	if !slices.Contains(providers, providerName) {
+		return field.ErrorList{field.NotSupported(providerPath, providerName, providers)}
+	}
+
+	if strings.TrimSpace(key) != key {
+		return field.ErrorList{field.Invalid(keyPath, key, "must not have leading or trailing whitespace")}
+	}
+
+	switch providerName {
+	case "aws":
+		if !awsKeyPattern.MatchString(key) {
+			return field.ErrorList{field.Invalid(keyPath, key, "must be a KMS key alias (alias/...), key ID or ARN")}
+		}
+	case "azure":
+		u, err := url.Parse(key)
+		if err != nil || u.Scheme != "https" || u.Host == "" || !strings.HasPrefix(u.Path, "/keys/") {
+			return field.ErrorList{field.Invalid(keyPath, key, "must be a Key Vault key URL (https://<vault>.vault.azure.net/keys/<name>)")}
+		}
+	case "gcp":
+		if !gcpKeyPattern.MatchString(key) {
+			return field.ErrorList{field.Invalid(keyPath, key, "must be a Cloud KMS key resource name (projects/.../cryptoKeys/...)")}
+		}
+	case "vault":
+		if strings.ContainsAny(key, " \t\n") {
+			return field.ErrorList{field.Invalid(keyPath, key, "must be a Vault transit key path")}
+		}
+	}
+	return nil
+}
+
+// appProviders lists the App providers available in this build. Provider
+// "secret" is served by ghait's file provider, which is not itself exposed on
+// an App.
+func appProviders() []string {
+	var providers []string
+	for _, name := range provider.Registered() {
+		switch name {
+		case "file":
+			providers = append(providers, "secret")
+		default:
+			providers = append(providers, name)
+		}
+	}
+	slices.Sort(providers)
+	return providers
+}
@@ -0,0 +1,117 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+)
+
+func TestValidateAppKey(t *testing.T) {
+	tests := []struct {
+		provider string
+		key      string
+		valid    bool
+	}{
+		{"aws", "alias/github-token-manager", true},
+		{"aws", "1234abcd-12ab-34cd-56ef-1234567890ab", true},
+		{"aws", "arn:aws:kms:eu-west-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab", true},
+		{"aws", "github-token-manager", false},
+		{"aws", " alias/github-token-manager", false},
+		{"azure", "https://my-vault.vault.azure.net/keys/github-app", true},
+		{"azure", "http://my-vault.vault.azure.net/keys/github-app", false},
+		{"azure", "https://my-vault.vault.azure.net/secrets/github-app", false},
+		{"gcp", "projects/p/locations/global/keyRings/r/cryptoKeys/k", true},
+		{"gcp", "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1", true},
+		{"gcp", "projects/p/keyRings/r", false},
+		{"vault", "transit/keys/github-app", true},
+		{"vault", "transit/keys/github app", false},
+		{"secret", "", true},
+		{"file", "/etc/key.pem", false},
+		{"pkcs11", "slot-0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.key, func(t *testing.T) {
+			errs := validateAppKey(tt.provider, tt.key)
+			if valid := len(errs) == 0; valid != tt.valid {
+				t.Errorf("validateAppKey(%q, %q) = %v, want valid=%t", tt.provider, tt.key, errs, tt.valid)
+			}
+		})
+	}
+}
+
+func TestAppCustomValidator_ValidateCreate(t *testing.T) {
+	keySecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "github-app-key"}}
+	secretApp := func(name string) *githubv1.App {
+		return &githubv1.App{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: name},
+			Spec: githubv1.AppSpec{
+				AppID:          1,
+				InstallationID: 2,
+				Provider:       "secret",
+				KeyRef:         &githubv1.KeySecretReference{Name: "github-app-key"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		app          *githubv1.App
+		objects      []client.Object
+		wantErr      string
+		wantWarnings int
+	}{
+		{
+			name:    "key secret exists",
+			app:     secretApp("present"),
+			objects: []client.Object{keySecret},
+		},
+		{
+			name:         "key secret missing",
+			app:          secretApp("missing"),
+			wantWarnings: 1,
+		},
+		{
+			name: "malformed aws key",
+			app: &githubv1.App{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "aws"},
+				Spec:       githubv1.AppSpec{AppID: 1, InstallationID: 2, Provider: "aws", Key: "not-a-key"},
+			},
+			wantErr: "spec.key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &AppCustomValidator{Client: testClient(t, tt.objects...)}
+			warnings, err := v.ValidateCreate(context.Background(), tt.app)
+			checkResult(t, warnings, err, tt.wantErr, tt.wantWarnings)
+		})
+	}
+}
+
+func TestClusterAppCustomValidator_ValidateCreate(t *testing.T) {
+	app := &githubv1.ClusterApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+		Spec: githubv1.ClusterAppSpec{
+			AppID:          1,
+			InstallationID: 2,
+			Provider:       "secret",
+			KeyRef:         &githubv1.ClusterKeySecretReference{Namespace: "platform-keys", Name: "github-app-key"},
+		},
+	}
+
+	v := &ClusterAppCustomValidator{Client: testClient(t)}
+	warnings, err := v.ValidateCreate(context.Background(), app)
+	checkResult(t, warnings, err, "", 1)
+
+	keySecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "platform-keys", Name: "github-app-key"}}
+	v = &ClusterAppCustomValidator{Client: testClient(t, keySecret)}
+	warnings, err = v.ValidateCreate(context.Background(), app)
+	checkResult(t, warnings, err, "", 0)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+)
+
+var clusterapplog = logf.Log.WithName("clusterapp-resource")
+
+// SetupClusterAppWebhookWithManager registers the validating webhook for
+// ClusterApp.
+func SetupClusterAppWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &githubv1.ClusterApp{}).
+		WithValidator(&ClusterAppCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-github-as-code-io-v1-clusterapp,mutating=false,failurePolicy=fail,sideEffects=None,groups=github.as-code.io,resources=clusterapps,verbs=create;update,versions=v1,name=vclusterapp-v1.kb.io,admissionReviewVersions=v1
+
+// ClusterAppCustomValidator validates ClusterApps on create and update.
+type ClusterAppCustomValidator struct {
+	Client client.Reader
+}
+
+// ValidateCreate implements [admission.Validator].
+func (v *ClusterAppCustomValidator) ValidateCreate(ctx context.Context, app *githubv1.ClusterApp) (admission.Warnings, error) {
+	clusterapplog.V(1).Info("validate create", "name", app.Name)
+	return v.validate(ctx, app)
+}
+
+// ValidateUpdate implements [admission.Validator].
+func (v *ClusterAppCustomValidator) ValidateUpdate(ctx context.Context, old, app *githubv1.ClusterApp) (admission.Warnings, error) {
+	clusterapplog.V(1).Info("validate update", "name", app.Name)
+	if specUnchanged(old, app, old.Spec, app.Spec) {
+		return nil, nil
+	}
+	return v.validate(ctx, app)
+}
+
+// ValidateDelete implements [admission.Validator].
+func (v *ClusterAppCustomValidator) ValidateDelete(context.Context, *githubv1.ClusterApp) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ClusterAppCustomValidator) validate(ctx context.Context, app *githubv1.ClusterApp) (admission.Warnings, error) {
+	errs := validateAppKey(app.Spec.Provider, app.Spec.Key)
+	if len(errs) > 0 {
+		return nil, apierrors.NewInvalid(githubv1.GroupVersion.WithKind("ClusterApp").GroupKind(), app.Name, errs)
+	}
+
+	var warnings admission.Warnings
+	if ref := app.Spec.KeyRef; ref != nil {
+		key := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+		if err := v.Client.Get(ctx, key, &corev1.Secret{}); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			warnings = append(warnings, fmt.Sprintf("Secret %s does not exist; the ClusterApp will not be Ready until it does", key))
+		}
+	}
+	return warnings, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/ghapp"
+	tm "github.com/isometry/github-token-manager/internal/tokenmanager"
+)
+
+var clustertokenlog = logf.Log.WithName("clustertoken-resource")
+
+// SetupClusterTokenWebhookWithManager registers the validating webhook for
+// ClusterToken.
+func SetupClusterTokenWebhookWithManager(mgr ctrl.Manager, registry *ghapp.Registry) error {
+	return ctrl.NewWebhookManagedBy(mgr, &githubv1.ClusterToken{}).
+		WithValidator(&ClusterTokenCustomValidator{Client: mgr.GetClient(), Registry: registry}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-github-as-code-io-v1-clustertoken,mutating=false,failurePolicy=fail,sideEffects=None,groups=github.as-code.io,resources=clustertokens,verbs=create;update,versions=v1,name=vclustertoken-v1.kb.io,admissionReviewVersions=v1
+
+// ClusterTokenCustomValidator validates ClusterTokens on create and update.
+type ClusterTokenCustomValidator struct {
+	Client client.Reader
+	// Registry, if set, is used to check requested permissions against
+	// those granted to the target installation.
+	Registry *ghapp.Registry
+}
+
+// ValidateCreate implements [admission.Validator].
+func (v *ClusterTokenCustomValidator) ValidateCreate(ctx context.Context, token *githubv1.ClusterToken) (admission.Warnings, error) {
+	clustertokenlog.V(1).Info("validate create", "name", token.Name)
+	return v.validate(ctx, nil, token)
+}
+
+// ValidateUpdate implements [admission.Validator].
+func (v *ClusterTokenCustomValidator) ValidateUpdate(ctx context.Context, old, token *githubv1.ClusterToken) (admission.Warnings, error) {
+	clustertokenlog.V(1).Info("validate update", "name", token.Name)
+	if !token.DeletionTimestamp.IsZero() {
+		// Finalizer removal must never be blocked.
+		return nil, nil
+	}
+	if specUnchanged(old, token, old.Spec, token.Spec) {
+		return nil, nil
+	}
+	return v.validate(ctx, old, token)
+}
+
+// ValidateDelete implements [admission.Validator].
+func (v *ClusterTokenCustomValidator) ValidateDelete(context.Context, *githubv1.ClusterToken) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ClusterTokenCustomValidator) validate(ctx context.Context, old tm.TokenManager, token *githubv1.ClusterToken) (admission.Warnings, error) {
+	warnings, err := validateTokenLike(ctx, v.Client, v.Registry, old, token)
+	if err != nil {
+		return warnings, err
+	}
+
+	// A missing target namespace is only a warning: with GitOps it is
+	// commonly created alongside, or after, the ClusterToken.
+	if namespace := token.GetSecretNamespace(); namespace != "" {
+		if err := v.Client.Get(ctx, client.ObjectKey{Name: namespace}, &corev1.Namespace{}); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return warnings, err
+			}
+			warnings = append(warnings, fmt.Sprintf("namespace %q does not exist; the Secret will be created once it does", namespace))
+		}
+	}
+	return warnings, nil
+}
@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+)
+
+func newClusterToken(name string, secret githubv1.ClusterTokenSecretSpec) *githubv1.ClusterToken {
+	return &githubv1.ClusterToken{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("uid-cluster-" + name)},
+		Spec: githubv1.ClusterTokenSpec{
+			Secret:          secret,
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+			RetryInterval:   metav1.Duration{Duration: 5 * time.Minute},
+		},
+	}
+}
+
+func TestClusterTokenCustomValidator_ValidateCreate(t *testing.T) {
+	teamA := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
+
+	tests := []struct {
+		name         string
+		token        *githubv1.ClusterToken
+		objects      []client.Object
+		wantErr      string
+		wantWarnings int
+	}{
+		{
+			name:    "valid",
+			token:   newClusterToken("valid", githubv1.ClusterTokenSecretSpec{Namespace: "team-a"}),
+			objects: []client.Object{teamA},
+		},
+		{
+			name:         "missing namespace",
+			token:        newClusterToken("early", githubv1.ClusterTokenSecretSpec{Namespace: "team-b"}),
+			wantWarnings: 1,
+		},
+		{
+			name: "namespace selector skips ownership checks",
+			token: newClusterToken("shared", githubv1.ClusterTokenSecretSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+			}),
+			objects: []client.Object{newToken("shared", nil)},
+		},
+		{
+			name:    "secret claimed by a token",
+			token:   newClusterToken("clash", githubv1.ClusterTokenSecretSpec{Namespace: "team-a", Name: "ci"}),
+			objects: []client.Object{teamA, newToken("ci", nil)},
+			wantErr: "already claimed by Token ci",
+		},
+		{
+			name:  "secret controlled by another clustertoken",
+			token: newClusterToken("clash", githubv1.ClusterTokenSecretSpec{Namespace: "team-a", Name: "ci"}),
+			objects: []client.Object{
+				teamA,
+				controlledSecret("team-a", "ci", newClusterToken("ci", githubv1.ClusterTokenSecretSpec{}), "ClusterToken"),
+			},
+			wantErr: "already managed by ClusterToken ci",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &ClusterTokenCustomValidator{Client: testClient(t, tt.objects...)}
+			warnings, err := v.ValidateCreate(context.Background(), tt.token)
+			checkResult(t, warnings, err, tt.wantErr, tt.wantWarnings)
+		})
+	}
+}
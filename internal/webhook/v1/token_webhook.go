@@ -0,0 +1,74 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/ghapp"
+)
+
+var tokenlog = logf.Log.WithName("token-resource")
+
+// SetupTokenWebhookWithManager registers the validating webhook for Token.
+func SetupTokenWebhookWithManager(mgr ctrl.Manager, registry *ghapp.Registry) error {
+	return ctrl.NewWebhookManagedBy(mgr, &githubv1.Token{}).
+		WithValidator(&TokenCustomValidator{Client: mgr.GetClient(), Registry: registry}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-github-as-code-io-v1-token,mutating=false,failurePolicy=fail,sideEffects=None,groups=github.as-code.io,resources=tokens,verbs=create;update,versions=v1,name=vtoken-v1.kb.io,admissionReviewVersions=v1
+
+// TokenCustomValidator validates Tokens on create and update.
+type TokenCustomValidator struct {
+	Client client.Reader
+	// Registry, if set, is used to check requested permissions against
+	// those granted to the target installation.
+	Registry *ghapp.Registry
+}
+
+// ValidateCreate implements [admission.Validator].
+func (v *TokenCustomValidator) ValidateCreate(ctx context.Context, token *githubv1.Token) (admission.Warnings, error) {
+	tokenlog.V(1).Info("validate create", "name", token.Name, "namespace", token.Namespace)
+	return validateTokenLike(ctx, v.Client, v.Registry, nil, token)
+}
+
+// ValidateUpdate implements [admission.Validator].
+func (v *TokenCustomValidator) ValidateUpdate(ctx context.Context, old, token *githubv1.Token) (admission.Warnings, error) {
+	tokenlog.V(1).Info("validate update", "name", token.Name, "namespace", token.Namespace)
+	if !token.DeletionTimestamp.IsZero() {
+		// Finalizer removal must never be blocked.
+		return nil, nil
+	}
+	if specUnchanged(old, token, old.Spec, token.Spec) {
+		// Metadata-only updates, such as the operator adding its finalizer,
+		// need not pay for GitHub and cluster-wide lookups.
+		return nil, nil
+	}
+	return validateTokenLike(ctx, v.Client, v.Registry, old, token)
+}
+
+// ValidateDelete implements [admission.Validator].
+func (v *TokenCustomValidator) ValidateDelete(context.Context, *githubv1.Token) (admission.Warnings, error) {
+	return nil, nil
+}
@@ -0,0 +1,348 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-github/v84/github"
+	"github.com/isometry/ghait/v84"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/ghapp"
+)
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := githubv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func testClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	return fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(objs...).Build()
+}
+
+func newToken(name string, mutate func(*githubv1.Token)) *githubv1.Token {
+	token := &githubv1.Token{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: name, UID: types.UID("uid-" + name)},
+		Spec: githubv1.TokenSpec{
+			RefreshInterval: metav1.Duration{Duration: 30 * time.Minute},
+			RetryInterval:   metav1.Duration{Duration: 5 * time.Minute},
+		},
+	}
+	if mutate != nil {
+		mutate(token)
+	}
+	return token
+}
+
+func controlledSecret(namespace, name string, owner metav1.Object, kind string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: githubv1.GroupVersion.String(),
+				Kind:       kind,
+				Name:       owner.GetName(),
+				UID:        owner.GetUID(),
+				Controller: new(true),
+			}},
+		},
+	}
+}
+
+func TestTokenCustomValidator_ValidateCreate(t *testing.T) {
+	other := newToken("other", func(tok *githubv1.Token) { tok.Spec.Secret.Name = "claimed" })
+
+	tests := []struct {
+		name         string
+		token        *githubv1.Token
+		objects      []client.Object
+		wantErr      string
+		wantWarnings int
+	}{
+		{
+			name:  "valid",
+			token: newToken("valid", nil),
+		},
+		{
+			name: "refresh interval beyond token validity",
+			token: newToken("slow", func(tok *githubv1.Token) {
+				tok.Spec.RefreshInterval.Duration = 2 * time.Hour
+			}),
+			wantErr: "spec.refreshInterval",
+		},
+		{
+			name: "refresh window beyond token validity",
+			token: newToken("early", func(tok *githubv1.Token) {
+				tok.Spec.RefreshWindow = &metav1.Duration{Duration: time.Hour}
+			}),
+			wantErr: "spec.refreshWindow",
+		},
+		{
+			name: "refresh jitter reaching token issue",
+			token: newToken("jittery", func(tok *githubv1.Token) {
+				tok.Spec.RefreshWindow = &metav1.Duration{Duration: 50 * time.Minute}
+				tok.Spec.RefreshJitter = &metav1.Duration{Duration: 10 * time.Minute}
+			}),
+			wantErr: "spec.refreshJitter",
+		},
+		{
+			name: "negative retry interval",
+			token: newToken("spin", func(tok *githubv1.Token) {
+				tok.Spec.RetryInterval = metav1.Duration{Duration: -time.Minute}
+			}),
+			wantErr: "spec.retryInterval",
+		},
+		{
+			name: "too many repositories",
+			token: newToken("wide", func(tok *githubv1.Token) {
+				tok.Spec.Repositories = make([]string, 400)
+				tok.Spec.RepositoryIDs = make([]int64, 101)
+			}),
+			wantErr: "spec.repositories",
+		},
+		{
+			name: "invalid repository name pattern",
+			token: newToken("glob", func(tok *githubv1.Token) {
+				tok.Spec.RepositorySelector = &githubv1.RepositorySelector{Names: []string{"service-*", "[service"}}
+			}),
+			wantErr: "spec.repositorySelector.names[1]",
+		},
+		{
+			name:    "secret controlled by another token",
+			token:   newToken("taken", nil),
+			objects: []client.Object{controlledSecret("team-a", "taken", other, "Token")},
+			wantErr: "already managed by Token other",
+		},
+		{
+			name:    "secret controlled by itself",
+			token:   newToken("mine", nil),
+			objects: []client.Object{controlledSecret("team-a", "mine", newToken("mine", nil), "Token")},
+		},
+		{
+			name: "secret claimed by another token",
+			token: newToken("late", func(tok *githubv1.Token) {
+				tok.Spec.Secret.Name = "claimed"
+			}),
+			objects: []client.Object{other},
+			wantErr: "already claimed by Token other",
+		},
+		{
+			name: "policy violation",
+			token: newToken("admin", func(tok *githubv1.Token) {
+				tok.Spec.Permissions = &githubv1.Permissions{Administration: github.Ptr("write")}
+			}),
+			objects: []client.Object{&githubv1.TokenPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "restricted"},
+				Spec: githubv1.TokenPolicySpec{TokenPolicyRules: githubv1.TokenPolicyRules{
+					Permissions: &githubv1.Permissions{Contents: github.Ptr("read")},
+				}},
+			}},
+			wantErr: "TokenPolicy restricted: permission administration=write is not allowed",
+		},
+		{
+			name: "secret template syntax error",
+			token: newToken("broken", func(tok *githubv1.Token) {
+				tok.Spec.Secret.Template = map[string]string{"token": "{{ .Token "}
+			}),
+			wantErr: "spec.secret.template[token]",
+		},
+		{
+			name: "secret template unknown field",
+			token: newToken("unknown", func(tok *githubv1.Token) {
+				tok.Spec.Secret.Template = map[string]string{"token": "{{ .Nope }}"}
+			}),
+			wantErr: "spec.secret.template[token]",
+		},
+		{
+			name: "secret template",
+			token: newToken("rendered", func(tok *githubv1.Token) {
+				tok.Spec.Secret.Template = map[string]string{
+					"expires-at": `{{ .ExpiresAt.Format "2006-01-02" }}`,
+					"repos":      `{{ join "," .Repositories }}`,
+				}
+			}),
+		},
+		{
+			name:         "unmanaged secret",
+			token:        newToken("adopt", nil),
+			objects:      []client.Object{&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "adopt"}}},
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &TokenCustomValidator{Client: testClient(t, tt.objects...)}
+			warnings, err := v.ValidateCreate(context.Background(), tt.token)
+			checkResult(t, warnings, err, tt.wantErr, tt.wantWarnings)
+		})
+	}
+}
+
+func TestTokenCustomValidator_ValidateUpdate_Deleting(t *testing.T) {
+	token := newToken("deleting", func(tok *githubv1.Token) {
+		tok.Spec.RefreshInterval.Duration = 0
+		tok.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	})
+
+	v := &TokenCustomValidator{Client: testClient(t)}
+	if _, err := v.ValidateUpdate(context.Background(), token, token); err != nil {
+		t.Errorf("ValidateUpdate() of deleting Token error = %v, want nil", err)
+	}
+}
+
+func TestTokenCustomValidator_ValidateUpdate(t *testing.T) {
+	ctx := context.Background()
+	other := newToken("other", func(tok *githubv1.Token) { tok.Spec.Secret.Name = "claimed" })
+	v := &TokenCustomValidator{Client: testClient(t, other, controlledSecret("team-a", "claimed", other, "Token"))}
+
+	// A Token admitted before other claimed its Secret is not rejected for
+	// the clash until it touches spec.secret.
+	old := newToken("mine", func(tok *githubv1.Token) { tok.Spec.Secret.Name = "claimed" })
+
+	relabelled := old.DeepCopy()
+	relabelled.Labels = map[string]string{"team": "a"}
+	relabelled.Finalizers = []string{"github.as-code.io/finalizer"}
+	warnings, err := v.ValidateUpdate(ctx, old, relabelled)
+	checkResult(t, warnings, err, "", 0)
+
+	refreshed := old.DeepCopy()
+	refreshed.Spec.RefreshInterval.Duration = 20 * time.Minute
+	warnings, err = v.ValidateUpdate(ctx, old, refreshed)
+	checkResult(t, warnings, err, "", 0)
+
+	invalid := old.DeepCopy()
+	invalid.Spec.RefreshInterval.Duration = 0
+	warnings, err = v.ValidateUpdate(ctx, old, invalid)
+	checkResult(t, warnings, err, "refreshInterval", 0)
+
+	renamed := newToken("mine", func(tok *githubv1.Token) { tok.Spec.Secret.Name = "mine" })
+	moved := renamed.DeepCopy()
+	moved.Spec.Secret.Name = "claimed"
+	warnings, err = v.ValidateUpdate(ctx, renamed, moved)
+	checkResult(t, warnings, err, "already managed by Token other", 0)
+}
+
+type fakeGHAIT struct{}
+
+func (fakeGHAIT) GetAppID() int64          { return 42 }
+func (fakeGHAIT) GetInstallationID() int64 { return 7 }
+func (fakeGHAIT) NewInstallationToken(context.Context, int64, *github.InstallationTokenOptions) (*github.InstallationToken, error) {
+	return nil, nil
+}
+func (fakeGHAIT) NewToken(context.Context) (*github.InstallationToken, error) { return nil, nil }
+func (fakeGHAIT) NewTokenWithOptions(context.Context, *github.InstallationTokenOptions) (*github.InstallationToken, error) {
+	return nil, nil
+}
+
+// fakeApps grants contents=read to installation 7, counting lookups.
+type fakeApps struct {
+	ghapp.AppsAPI
+	calls *int
+}
+
+func (a fakeApps) GetInstallation(_ context.Context, id int64) (*github.Installation, *github.Response, error) {
+	*a.calls++
+	return &github.Installation{
+		ID:          github.Ptr(id),
+		Permissions: &github.InstallationPermissions{Contents: github.Ptr("read")},
+	}, &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}, nil
+}
+
+// fakeSigner stands in for the App's key provider.
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(jwt.Claims) (string, error) { return "signed", nil }
+
+func withFakeSigner() ghapp.Option {
+	return ghapp.WithSigner(func(context.Context, *ghapp.OperatorConfig) (ghinstallation.Signer, error) {
+		return fakeSigner{}, nil
+	})
+}
+
+func TestTokenCustomValidator_InstallationPermissions(t *testing.T) {
+	ctx := context.Background()
+	var calls int
+	newRegistry := func(apps ghapp.AppsFactoryFunc) *ghapp.Registry {
+		reg := ghapp.NewRegistry("gtm-system", nil,
+			withFakeSigner(),
+			ghapp.WithFactory(func(context.Context, *ghapp.OperatorConfig, *github.Client) (ghait.GHAIT, error) {
+				return fakeGHAIT{}, nil
+			}),
+			ghapp.WithAppsFactory(apps))
+		if _, err := reg.ForApp(ctx, ghapp.Key{Namespace: "team-b", Name: "shared"}, "1", &ghapp.OperatorConfig{AppID: 42}); err != nil {
+			t.Fatalf("ForApp() err = %v", err)
+		}
+		return reg
+	}
+	reg := newRegistry(func(*github.Client) (ghapp.AppsAPI, error) { return fakeApps{calls: &calls}, nil })
+	token := newToken("shared", func(tok *githubv1.Token) {
+		tok.Spec.AppRef = &githubv1.LocalAppReference{Name: "shared", Namespace: "team-b"}
+		tok.Spec.Permissions = &githubv1.Permissions{Contents: github.Ptr("write")}
+	})
+
+	// Without an AppGrant the installation of the other namespace's App is
+	// not consulted.
+	v := &TokenCustomValidator{Client: testClient(t), Registry: reg}
+	warnings, err := v.ValidateCreate(ctx, token)
+	checkResult(t, warnings, err, "", 0)
+	if calls != 0 {
+		t.Errorf("installation looked up %d times without an AppGrant, want 0", calls)
+	}
+
+	grant := &githubv1.AppGrant{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "team-a"},
+		Spec:       githubv1.AppGrantSpec{Namespaces: []string{"team-a"}},
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
+	v = &TokenCustomValidator{Client: testClient(t, grant, namespace), Registry: reg}
+	warnings, err = v.ValidateCreate(ctx, token)
+	checkResult(t, warnings, err, "exceed those granted to installation 7: contents=write", 0)
+
+	// An App that cannot look up installations is reported, not skipped.
+	v.Registry = newRegistry(func(*github.Client) (ghapp.AppsAPI, error) { return nil, errors.New("apps unavailable") })
+	warnings, err = v.ValidateCreate(ctx, token)
+	checkResult(t, warnings, err, "", 1)
+}
+
+func checkResult(t *testing.T, warnings []string, err error, wantErr string, wantWarnings int) {
+	t.Helper()
+	if wantErr == "" {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	} else {
+		if !apierrors.IsInvalid(err) {
+			t.Fatalf("error = %v, want Invalid", err)
+		}
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("error = %q, want it to contain %q", err, wantErr)
+		}
+	}
+	if len(warnings) != wantWarnings {
+		t.Errorf("warnings = %v, want %d", warnings, wantWarnings)
+	}
+}
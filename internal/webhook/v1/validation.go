@@ -0,0 +1,258 @@
+/*
+Copyright 2024 Robin Breathe.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	githubv1 "github.com/isometry/github-token-manager/api/v1"
+	"github.com/isometry/github-token-manager/internal/ghapp"
+	"github.com/isometry/github-token-manager/internal/policy"
+	tm "github.com/isometry/github-token-manager/internal/tokenmanager"
+)
+
+// MaxRepositories is the most repositories GitHub will scope a single
+// installation token to, counting spec.repositories and spec.repositoryIDs
+// together.
+const MaxRepositories = githubv1.MaxTokenRepositories
+
+var specPath = field.NewPath("spec")
+
+// validateIntervals rejects refresh and retry intervals, refresh windows and
+// jitters that would either spin the reconciler or let the Secret outlive its
+// token.
+func validateIntervals(owner tm.TokenManager) field.ErrorList {
+	var errs field.ErrorList
+	refresh := owner.GetRefreshInterval()
+	if refresh <= 0 || refresh > ghapp.TokenValidity {
+		errs = append(errs, field.Invalid(specPath.Child("refreshInterval"), refresh.String(),
+			fmt.Sprintf("must be greater than 0 and at most %s", ghapp.TokenValidity)))
+	}
+	window := owner.GetRefreshWindow()
+	if window < 0 || window >= ghapp.TokenValidity {
+		errs = append(errs, field.Invalid(specPath.Child("refreshWindow"), window.String(),
+			fmt.Sprintf("must be at least 0 and less than %s", ghapp.TokenValidity)))
+	} else if jitter := owner.GetRefreshJitter(); jitter < 0 || window+jitter >= ghapp.TokenValidity {
+		errs = append(errs, field.Invalid(specPath.Child("refreshJitter"), jitter.String(),
+			fmt.Sprintf("must be at least 0 and, with refreshWindow, less than %s", ghapp.TokenValidity)))
+	}
+	if retry := owner.GetRetryInterval(); retry < 0 || retry > ghapp.TokenValidity {
+		errs = append(errs, field.Invalid(specPath.Child("retryInterval"), retry.String(),
+			fmt.Sprintf("must be at least 0 and at most %s", ghapp.TokenValidity)))
+	}
+	return errs
+}
+
+// validateRepositories rejects more repositories than GitHub will scope a
+// token to.
+func validateRepositories(owner tm.TokenManager) field.ErrorList {
+	options := owner.GetInstallationTokenOptions()
+	if n := len(options.Repositories) + len(options.RepositoryIDs); n > MaxRepositories {
+		return field.ErrorList{field.TooMany(specPath.Child("repositories"), n, MaxRepositories)}
+	}
+	return nil
+}
+
+// validateRepositorySelector rejects repository name patterns that are not
+// valid globs, which would otherwise never match.
+func validateRepositorySelector(owner tm.TokenManager) field.ErrorList {
+	selector := owner.GetRepositorySelector()
+	if selector == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	namesPath := specPath.Child("repositorySelector", "names")
+	for i, pattern := range selector.Names {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, field.Invalid(namesPath.Index(i), pattern, err.Error()))
+		}
+	}
+	return errs
+}
+
+// validateSecretTemplate rejects a secret template entry that fails to parse
+// or to render against placeholder inputs, e.g. one referencing an unknown
+// field.
+func validateSecretTemplate(owner tm.TokenManager) field.ErrorList {
+	tmpl := owner.GetSecretTemplate()
+	err := tm.ValidateSecretTemplate(tmpl)
+	if err == nil {
+		return nil
+	}
+	templatePath := specPath.Child("secret", "template")
+	var templateErr *tm.TemplateError
+	if errors.As(err, &templateErr) {
+		return field.ErrorList{field.Invalid(templatePath.Key(templateErr.Key), tmpl[templateErr.Key], templateErr.Err.Error())}
+	}
+	return field.ErrorList{field.Invalid(templatePath, tmpl, err.Error())}
+}
+
+// validateSecretOwnership rejects a Secret name already claimed by another
+// Token or ClusterToken, whether through an existing Secret it controls or
+// through its spec. An existing Secret with no controller only warrants a
+// warning, as reconcile will report it on the Ready condition.
+func validateSecretOwnership(ctx context.Context, c client.Reader, owner tm.TokenManager, secretPath *field.Path) (admission.Warnings, field.ErrorList) {
+	key := types.NamespacedName{Namespace: owner.GetSecretNamespace(), Name: owner.GetSecretName()}
+	if key.Namespace == "" {
+		// Fanned out by namespaceSelector; collisions are per namespace and
+		// reported at reconcile.
+		return nil, nil
+	}
+	namePath := secretPath.Child("name")
+
+	var warnings admission.Warnings
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, key, secret)
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return nil, field.ErrorList{field.InternalError(namePath, err)}
+	default:
+		ref := metav1.GetControllerOf(secret)
+		switch {
+		case ref == nil:
+			warnings = append(warnings, fmt.Sprintf("Secret %s already exists and is not managed by github-token-manager", key))
+		case !isSelf(ref, owner):
+			return nil, field.ErrorList{field.Duplicate(namePath,
+				fmt.Sprintf("Secret %s is already managed by %s %s", key, ref.Kind, ref.Name))}
+		}
+	}
+
+	var tokens githubv1.TokenList
+	if err := c.List(ctx, &tokens, client.InNamespace(key.Namespace)); err != nil {
+		return nil, field.ErrorList{field.InternalError(namePath, err)}
+	}
+	for i := range tokens.Items {
+		if other := &tokens.Items[i]; claims(other, owner, key) {
+			return nil, field.ErrorList{field.Duplicate(namePath,
+				fmt.Sprintf("Secret %s is already claimed by Token %s", key, other.Name))}
+		}
+	}
+
+	var clusterTokens githubv1.ClusterTokenList
+	if err := c.List(ctx, &clusterTokens); err != nil {
+		return nil, field.ErrorList{field.InternalError(namePath, err)}
+	}
+	for i := range clusterTokens.Items {
+		if other := &clusterTokens.Items[i]; claims(other, owner, key) {
+			return nil, field.ErrorList{field.Duplicate(namePath,
+				fmt.Sprintf("Secret %s is already claimed by ClusterToken %s", key, other.Name))}
+		}
+	}
+
+	return warnings, nil
+}
+
+// isSelf reports whether ref points at owner. A Token recreated under the
+// same name is treated as itself so that it can adopt its predecessor's
+// Secret once that is garbage collected.
+func isSelf(ref *metav1.OwnerReference, owner tm.TokenManager) bool {
+	if owner.GetUID() != "" && ref.UID == owner.GetUID() {
+		return true
+	}
+	return ref.Kind == owner.GetType() && ref.Name == owner.GetName()
+}
+
+// claims reports whether other, a different object from owner, targets the
+// Secret named by key.
+func claims(other, owner tm.TokenManager, key types.NamespacedName) bool {
+	if other.GetType() == owner.GetType() && other.GetName() == owner.GetName() && other.GetNamespace() == owner.GetNamespace() {
+		return false
+	}
+	return other.GetSecretNamespace() == key.Namespace && other.GetSecretName() == key.Name
+}
+
+// specUnchanged reports whether an update leaves the spec of obj as it was
+// in old, as for the status, finalizer and label updates the operator makes
+// itself. The generation, which the API server bumps on every spec change,
+// is checked first as it is cheaper than comparing the specs.
+func specUnchanged(old, obj metav1.Object, oldSpec, spec any) bool {
+	if old.GetGeneration() != 0 && old.GetGeneration() == obj.GetGeneration() {
+		return true
+	}
+	return equality.Semantic.DeepEqual(oldSpec, spec)
+}
+
+// validateTokenLike runs the validations shared by Token and ClusterToken,
+// including a dry render of any secret template, any TokenPolicy or
+// ClusterTokenPolicy that applies and, when reg is non-nil and knows the App,
+// the permissions granted to the installation. On update, old is the object
+// being replaced, and the Secret's ownership is only rechecked if its name or
+// namespace changed; on create, old is nil.
+func validateTokenLike(ctx context.Context, c client.Reader, reg *ghapp.Registry, old, owner tm.TokenManager) (admission.Warnings, error) {
+	errs := validateIntervals(owner)
+	errs = append(errs, validateRepositories(owner)...)
+	errs = append(errs, validateRepositorySelector(owner)...)
+	errs = append(errs, validateSecretTemplate(owner)...)
+	var warnings admission.Warnings
+	if old == nil || old.GetSecretName() != owner.GetSecretName() || old.GetSecretNamespace() != owner.GetSecretNamespace() {
+		var ownershipErrs field.ErrorList
+		warnings, ownershipErrs = validateSecretOwnership(ctx, c, owner, specPath.Child("secret"))
+		errs = append(errs, ownershipErrs...)
+	}
+	violations, err := policy.Evaluate(ctx, c, owner)
+	if err != nil {
+		return warnings, err
+	}
+	for _, violation := range violations {
+		errs = append(errs, field.Forbidden(specPath, violation))
+	}
+	if reg != nil {
+		exceeded, err := checkInstallation(ctx, c, reg, owner)
+		if err != nil {
+			// The controller rechecks before minting, so GitHub being
+			// unreachable must not block admission.
+			logf.FromContext(ctx).Error(err, "failed to check installation permissions", "name", owner.GetName())
+			warnings = append(warnings, fmt.Sprintf("installation permissions not checked: %v", err))
+		}
+		if exceeded != "" {
+			errs = append(errs, field.Forbidden(specPath.Child("permissions"), exceeded))
+		}
+	}
+	if len(errs) > 0 {
+		return warnings, apierrors.NewInvalid(githubv1.GroupVersion.WithKind(owner.GetType()).GroupKind(), owner.GetName(), errs)
+	}
+	return warnings, nil
+}
+
+// checkInstallation checks the permissions owner requests against those
+// granted to its App's installation. A Token referencing an App in another
+// namespace is only checked once an AppGrant permits it, so that admission
+// reveals nothing about the installations of Apps it may not use.
+func checkInstallation(ctx context.Context, c client.Reader, reg *ghapp.Registry, owner tm.TokenManager) (string, error) {
+	key := policy.AppKey(owner.GetAppRef(), reg.OperatorNamespace())
+	if from := owner.GetNamespace(); from != "" && key.Namespace != "" && key.Namespace != from {
+		granted, err := policy.AppGranted(ctx, c, types.NamespacedName{Namespace: key.Namespace, Name: key.Name}, from)
+		if err != nil || !granted {
+			return "", err
+		}
+	}
+	return policy.CheckInstallation(ctx, reg, key, owner)
+}